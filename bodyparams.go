@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+)
+
+// bodyParam is an injectable value found in a structured request body.
+type bodyParam struct {
+	name  string
+	loc   paramLocation
+	value string
+}
+
+// structuredParams lists the injectable values of tmpl's body when it is
+// one of the structured formats kxss can rewrite.
+func structuredParams(tmpl *requestTemplate) []bodyParam {
+	if tmpl == nil || tmpl.Body == "" {
+		return nil
+	}
+	ct := tmpl.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(ct, "multipart/form-data"):
+		return multipartParams(tmpl.Body, multipartBoundary(ct))
+	case strings.Contains(ct, "json"):
+		return jsonParams(tmpl.Body)
+	case strings.Contains(ct, "xml"):
+		return xmlParams(tmpl.Body)
+	}
+	return nil
+}
+
+// editStructuredBody rewrites the value of c's parameter in body with edit
+// when c is a structured body parameter; ok is false otherwise.
+func editStructuredBody(c paramCheck, body string, edit func(string) string) (out string, ok bool, err error) {
+	switch c.loc {
+	case locJSON, locJSONKey:
+		out, err = editJSON(body, c.param, c.loc == locJSONKey, edit)
+		return out, true, err
+	case locXML:
+		out, err = editXML(body, c.param, edit)
+		return out, true, err
+	case locMultipart, locMultipartFile:
+		var boundary string
+		if c.tmpl != nil {
+			boundary = multipartBoundary(c.tmpl.Header.Get("Content-Type"))
+		}
+		out, err = editMultipart(body, boundary, c.param, c.loc == locMultipartFile, edit)
+		return out, true, err
+	}
+	return "", false, nil
+}
+
+// bodyContentType is the Content-Type a body parameter in loc is sent
+// with, or "" for parameters that are not in the body. Multipart bodies
+// carry their boundary in body.
+func bodyContentType(loc paramLocation, body string) string {
+	switch loc {
+	case locBody:
+		return "application/x-www-form-urlencoded"
+	case locJSON, locJSONKey:
+		return "application/json"
+	case locXML:
+		return "text/xml; charset=utf-8"
+	case locMultipart, locMultipartFile:
+		return multipartContentType(body)
+	}
+	return ""
+}
+
+// reflectableType reports whether a response of content type ct can carry
+// a reflection for a parameter in loc: HTML always, and JSON or XML for
+// parameters sent in a body of the same kind. Multipart fields only count
+// in HTML.
+func reflectableType(ct string, loc paramLocation) bool {
+	switch {
+	case ct == "", strings.Contains(ct, "html"):
+		return true
+	case loc == locJSON || loc == locJSONKey:
+		return strings.Contains(ct, "json")
+	case loc == locXML:
+		return strings.Contains(ct, "xml")
+	}
+	return false
+}
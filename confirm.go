@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"strings"
+)
+
+// confirmFindings is set by -verify to try a harmless full payload on
+// promising findings.
+var confirmFindings bool
+
+// probeMarker is an attribute name that only shows up in a tag position
+// when a confirmation payload created markup.
+const probeMarker = "data-kxss-probe"
+
+// Confirmation payloads. Each one adds probeMarker as an attribute of a
+// new or an existing tag once it lands intact.
+var (
+	probeTag          = "<kxss-probe " + probeMarker + "=1>"
+	probeTagPayloads  = []string{probeTag, "</title></textarea>" + probeTag}
+	probeAttrPayloads = []string{`">` + probeTag, `'>` + probeTag, `" ` + probeMarker + `=1 x="`, `' ` + probeMarker + `=1 x='`}
+)
+
+// confirmPayloads returns the payloads worth trying for r, given where it
+// is reflected and which characters survived.
+func confirmPayloads(r Result) []string {
+	survived := make(map[string]bool, len(r.Unfiltered))
+	for _, c := range r.Unfiltered {
+		survived[c] = true
+	}
+	tags := survived["<"] && survived[">"]
+	var out []string
+	for _, ctx := range r.Context {
+		switch ctx {
+		case ctxAttribute, ctxURL, ctxEventHandler, ctxTag:
+			for _, p := range probeAttrPayloads {
+				if survived[p[:1]] && (tags || !strings.Contains(p, "<")) {
+					out = append(out, p)
+				}
+			}
+		case ctxScript:
+			if tags {
+				out = append(out, "</script>"+probeTag)
+			}
+		case ctxStyle:
+			if tags {
+				out = append(out, "</style>"+probeTag)
+			}
+		case ctxComment:
+			if tags {
+				out = append(out, "-->"+probeTag)
+			}
+		default:
+			if tags {
+				out = append(out, probeTagPayloads...)
+			}
+		}
+	}
+	return out
+}
+
+// confirmXSS sends the payloads of confirmPayloads appended to c's
+// parameter until one comes back as markup, and returns it with the part
+// of the response around it.
+func confirmXSS(c paramCheck, r Result) (payload, evidence string, ok bool) {
+	seen := make(map[string]bool)
+	for _, p := range confirmPayloads(r) {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		testURL, testBody, err := c.withSuffix(p)
+		if err != nil {
+			return "", "", false
+		}
+		resp, err := c.withHeaderSuffix(p).send(testURL, testBody)
+		if err != nil {
+			return "", "", false
+		}
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+		body := string(b)
+		for _, ctx := range reflectionContexts(body, probeMarker) {
+			if ctx == ctxTag {
+				return p, snippetAround(body, probeMarker, evidenceContext), true
+			}
+		}
+	}
+	return "", "", false
+}
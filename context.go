@@ -0,0 +1,217 @@
+package main
+
+import (
+	"strings"
+)
+
+// Contexts a reflection can land in. Which special characters matter
+// depends on them: < for text, the quote for attributes, and so on.
+const (
+	ctxText         = "text"          // between tags
+	ctxAttribute    = "attribute"     // attribute value
+	ctxURL          = "url"           // value of an attribute holding a URL
+	ctxEventHandler = "event-handler" // value of an on* attribute
+	ctxScript       = "script"        // inside a script element
+	ctxStyle        = "style"         // inside a style element
+	ctxComment      = "comment"       // inside an HTML comment
+	ctxRCDATA       = "rcdata"        // inside a textarea or title element
+	ctxTag          = "tag"           // inside a tag but outside attribute values
+)
+
+// urlAttributes are the attributes whose values browsers treat as URLs.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true,
+	"data": true, "poster": true, "background": true, "cite": true,
+	"codebase": true, "longdesc": true, "xlink:href": true, "srcset": true,
+}
+
+// rawTextElements hold text that runs until their end tag, markup
+// included.
+var rawTextElements = map[string]string{
+	"script":   ctxScript,
+	"style":    ctxStyle,
+	"textarea": ctxRCDATA,
+	"title":    ctxRCDATA,
+}
+
+// reflectionContexts tokenizes body just far enough to tell where each
+// occurrence of marker sits, and returns the distinct contexts in order
+// of appearance. An occurrence is in the context its first byte is in,
+// as a probe character after the canary may well end that context.
+func reflectionContexts(body, marker string) []string {
+	var at []int
+	for i := 0; ; {
+		j := strings.Index(body[i:], marker)
+		if j < 0 {
+			break
+		}
+		at = append(at, i+j)
+		i += j + 1
+	}
+	if len(at) == 0 {
+		return nil
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	add := func(start, end int, ctx string) {
+		if seen[ctx] {
+			return
+		}
+		for _, a := range at {
+			if start <= a && a < end {
+				seen[ctx] = true
+				out = append(out, ctx)
+				return
+			}
+		}
+	}
+
+	lower := strings.ToLower(body)
+	i := 0
+	for i < len(body) {
+		lt := strings.IndexByte(body[i:], '<')
+		if lt < 0 {
+			add(i, len(body), ctxText)
+			break
+		}
+		add(i, i+lt, ctxText)
+		i += lt
+
+		if strings.HasPrefix(body[i:], "<!--") {
+			end := strings.Index(body[i+4:], "-->")
+			if end < 0 {
+				add(i, len(body), ctxComment)
+				break
+			}
+			add(i, i+4+end, ctxComment)
+			i += 4 + end + 3
+			continue
+		}
+		name, end := scanTag(body, i, add)
+		if end < 0 {
+			break
+		}
+		i = end
+		if ctx, ok := rawTextElements[name]; ok {
+			endTag := strings.Index(lower[i:], "</"+name)
+			if endTag < 0 {
+				add(i, len(body), ctx)
+				break
+			}
+			add(i, i+endTag, ctx)
+			i += endTag
+		}
+	}
+	return out
+}
+
+// reflectedWhereItMatters reports whether marker, the canary followed by
+// a probe, comes back where the probe's last character can do something.
+// Markup is not parsed in comments and textarea or title elements, so
+// only < and >, which can end them, count there. The canary alone counts
+// anywhere, as it only shows that the value is reflected.
+func reflectedWhereItMatters(body, marker string) bool {
+	contexts := reflectionContexts(body, marker)
+	if marker == reflectionCanary || strings.HasSuffix(marker, "<") || strings.HasSuffix(marker, ">") {
+		return len(contexts) > 0
+	}
+	for _, ctx := range contexts {
+		if ctx != ctxComment && ctx != ctxRCDATA {
+			return true
+		}
+	}
+	return false
+}
+
+// scanTag reads the tag starting at body[start] == '<', passing the
+// pieces of it to add, and returns the lower-cased name of a start tag
+// and the index just past it. A '<' that opens no tag is skipped as
+// text; end is -1 when the tag runs to the end of body.
+func scanTag(body string, start int, add func(start, end int, ctx string)) (string, int) {
+	i := start + 1
+	closing := i < len(body) && body[i] == '/'
+	if closing {
+		i++
+	}
+	if i >= len(body) || !isASCIILetter(body[i]) {
+		add(start, i, ctxText)
+		return "", i
+	}
+	nameStart := i
+	for i < len(body) && !isTagSpace(body[i]) && body[i] != '>' && body[i] != '/' {
+		i++
+	}
+	name := strings.ToLower(body[nameStart:i])
+	if closing {
+		name = ""
+	}
+
+	for i < len(body) {
+		from := i
+		for i < len(body) && (isTagSpace(body[i]) || body[i] == '/') {
+			i++
+		}
+		if i < len(body) && body[i] == '>' {
+			add(from, i, ctxTag)
+			return name, i + 1
+		}
+		attrStart := i
+		for i < len(body) && !isTagSpace(body[i]) && body[i] != '=' && body[i] != '>' {
+			i++
+		}
+		attr := strings.ToLower(body[attrStart:i])
+		for i < len(body) && isTagSpace(body[i]) {
+			i++
+		}
+		add(from, i, ctxTag)
+		if i >= len(body) || body[i] != '=' {
+			continue
+		}
+		i++
+		for i < len(body) && isTagSpace(body[i]) {
+			i++
+		}
+		if i >= len(body) {
+			break
+		}
+		valueStart, valueEnd := i, 0
+		if q := body[i]; q == '"' || q == '\'' {
+			valueStart++
+			end := strings.IndexByte(body[valueStart:], q)
+			if end < 0 {
+				add(valueStart, len(body), attributeContext(attr))
+				return name, -1
+			}
+			valueEnd = valueStart + end
+			i = valueEnd + 1
+		} else {
+			for i < len(body) && !isTagSpace(body[i]) && body[i] != '>' {
+				i++
+			}
+			valueEnd = i
+		}
+		add(valueStart, valueEnd, attributeContext(attr))
+	}
+	add(start, len(body), ctxTag)
+	return name, -1
+}
+
+// attributeContext is the context of a value of the named attribute.
+func attributeContext(attr string) string {
+	switch {
+	case strings.HasPrefix(attr, "on"):
+		return ctxEventHandler
+	case urlAttributes[attr]:
+		return ctxURL
+	}
+	return ctxAttribute
+}
+
+func isASCIILetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func isTagSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReflectionContexts(t *testing.T) {
+	const m = "CANARY"
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"plain CANARY text", []string{ctxText}},
+		{"<p>CANARY</p>", []string{ctxText}},
+		{`<input value="CANARY">`, []string{ctxAttribute}},
+		{`<input value='CANARY'>`, []string{ctxAttribute}},
+		{`<input value=CANARY>`, []string{ctxAttribute}},
+		{`<a href="/x?q=CANARY">`, []string{ctxURL}},
+		{`<img src=x onerror="f('CANARY')">`, []string{ctxEventHandler}},
+		{`<div CANARY>`, []string{ctxTag}},
+		{`<script>var q = "CANARY";</script>`, []string{ctxScript}},
+		{`<style>.CANARY{}</style>`, []string{ctxStyle}},
+		{`<!-- CANARY -->`, []string{ctxComment}},
+		{`<textarea>CANARY</textarea>`, []string{ctxRCDATA}},
+		{`<TITLE>CANARY</TITLE>`, []string{ctxRCDATA}},
+		// markup inside raw text elements is not parsed
+		{`<script>"<a href='CANARY'>"</script>`, []string{ctxScript}},
+		{`<textarea><b title="CANARY"></textarea>`, []string{ctxRCDATA}},
+		// a < that opens no tag is text
+		{`1 < 2 CANARY`, []string{ctxText}},
+		// distinct contexts in order of appearance
+		{`<a title="CANARY">CANARY</a><!--CANARY--><b>CANARY</b>`, []string{ctxAttribute, ctxText, ctxComment}},
+		// unterminated constructs run to the end of the body
+		{`<!-- CANARY`, []string{ctxComment}},
+		{`<input value="CANARY`, []string{ctxAttribute}},
+		{`<script>CANARY`, []string{ctxScript}},
+		{"nothing here", nil},
+	}
+	for _, tt := range tests {
+		if got := reflectionContexts(tt.body, m); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("reflectionContexts(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+// TestReflectionContextsProbe checks that an occurrence counts in the
+// context it starts in when the probe character after the canary ends
+// that context.
+func TestReflectionContextsProbe(t *testing.T) {
+	tests := []struct {
+		body, marker string
+		want         []string
+	}{
+		{`<input value="CANARY"">`, `CANARY"`, []string{ctxAttribute}},
+		{`<p>CANARY<</p>`, `CANARY<`, []string{ctxText}},
+		{`<p>CANARY</p>`, `CANARY<`, []string{ctxText}},
+		{`<!-- CANARY> -->`, `CANARY>`, []string{ctxComment}},
+	}
+	for _, tt := range tests {
+		if got := reflectionContexts(tt.body, tt.marker); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("reflectionContexts(%q, %q) = %v, want %v", tt.body, tt.marker, got, tt.want)
+		}
+	}
+}
+
+func TestReflectedWhereItMatters(t *testing.T) {
+	c := reflectionCanary
+	tests := []struct {
+		body, marker string
+		want         bool
+	}{
+		{"<p>" + c + `"</p>`, c + `"`, true},
+		{"<!-- " + c + `" -->`, c + `"`, false},
+		{"<textarea>" + c + `'</textarea>`, c + "'", false},
+		{"<title>" + c + "(</title>", c + "(", false},
+		{"<textarea>" + c + "<</textarea>", c + "<", true},
+		{"<!-- " + c + "> -->", c + ">", true},
+		{"<!-- " + c + `" -->` + "<b>" + c + `"</b>`, c + `"`, true},
+		{"<!-- " + c + " -->", c, true},
+		{"<p>nothing</p>", c + "<", false},
+	}
+	for _, tt := range tests {
+		if got := reflectedWhereItMatters(tt.body, tt.marker); got != tt.want {
+			t.Errorf("reflectedWhereItMatters(%q, %q) = %v, want %v", tt.body, tt.marker, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// addCookieHeader adds a -cookie value to the headers sent with every
+// request, merging it into an existing Cookie header.
+func addCookieHeader(cookies string) {
+	cookies = strings.TrimSpace(cookies)
+	if prev := extraHeaders.Get("Cookie"); prev != "" {
+		cookies = prev + "; " + cookies
+	}
+	extraHeaders.Set("Cookie", cookies)
+}
+
+// loadCookieJar reads a Netscape cookies.txt file (as written by curl,
+// wget and browser extensions) into a jar for the shared client. Cookies
+// set by responses during the scan update the jar.
+func loadCookieJar(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(file)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = rest, true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", lineNo, len(fields))
+		}
+		domain, includeSubs, path, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+		c := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(includeSubs, "TRUE") {
+			c.Domain = domain
+		}
+		if secs, err := strconv.ParseInt(expires, 10, 64); err == nil && secs > 0 {
+			c.Expires = time.Unix(secs, 0)
+		}
+
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: path}
+		jar.SetCookies(u, []*http.Cookie{c})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return jar, nil
+}
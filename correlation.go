@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// headerScanID is the scan ID under -scan-header: every request then
+// carries it and an ID of its own, so the target's logs can be tied to the
+// scan and its traffic allowlisted.
+var headerScanID string
+
+// addCorrelationHeaders sets X-Kxss-Scan and X-Kxss-Request on header
+// under -scan-header.
+func addCorrelationHeaders(header http.Header) {
+	if headerScanID == "" {
+		return
+	}
+	header.Set("X-Kxss-Scan", headerScanID)
+	header.Set("X-Kxss-Request", newUUID())
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
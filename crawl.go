@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxCrawlPages caps how many pages are fetched per origin, so a single
+// calendar or faceted search cannot keep the crawler busy forever.
+const maxCrawlPages = 500
+
+// siteCrawler spiders same-origin links and forms from each seed URL and
+// emits every parameterized URL and form it finds as a new check.
+type siteCrawler struct {
+	depth int
+
+	mu      sync.Mutex
+	visited map[string]bool
+	pages   map[string]int
+	emitted map[string]bool
+}
+
+func newSiteCrawler(depth int) *siteCrawler {
+	return &siteCrawler{
+		depth:   depth,
+		visited: make(map[string]bool),
+		pages:   make(map[string]int),
+		emitted: make(map[string]bool),
+	}
+}
+
+// expand is a workerFunc passing c on, followed by the checks found by
+// crawling from it.
+func (s *siteCrawler) expand(c paramCheck, output chan paramCheck) {
+	output <- c
+
+	type page struct {
+		url   string
+		depth int
+	}
+	queue := []page{{c.url, 0}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if !s.visit(p.url) {
+			continue
+		}
+
+		links, forms := s.fetch(p.url)
+		for _, link := range links {
+			if u, err := url.Parse(link); err == nil && u.RawQuery != "" {
+				s.emit(paramCheck{url: link}, output)
+			}
+			if p.depth+1 <= s.depth {
+				queue = append(queue, page{link, p.depth + 1})
+			}
+		}
+		for _, form := range forms {
+			if fc, ok := formCheck(form); ok {
+				s.emit(fc, output)
+			}
+		}
+	}
+}
+
+// visit marks pageURL as crawled, reporting false if it already was or
+// its origin has reached maxCrawlPages.
+func (s *siteCrawler) visit(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	u.Fragment = ""
+	key := u.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.visited[key] || s.pages[u.Host] >= maxCrawlPages {
+		return false
+	}
+	s.visited[key] = true
+	s.pages[u.Host]++
+	return true
+}
+
+func (s *siteCrawler) emit(c paramCheck, output chan paramCheck) {
+	key := c.url
+	if c.tmpl != nil {
+		key = c.tmpl.Method + " " + c.url + " " + c.tmpl.Body
+	}
+	s.mu.Lock()
+	seen := s.emitted[key]
+	s.emitted[key] = true
+	s.mu.Unlock()
+	if !seen && scope.allows(c.url) {
+		output <- c
+	}
+}
+
+// fetch downloads an HTML page and returns its same-origin links and its
+// forms, with relative URLs resolved against the page.
+func (s *siteCrawler) fetch(pageURL string) ([]string, []crawlerForm) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, nil
+	}
+	resp, err := doRequestWithRetries("GET", pageURL, nil, "", 1)
+	if err != nil {
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return nil, nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, nil
+	}
+
+	resolve := func(ref string) (string, bool) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "#") {
+			return "", false
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host != base.Host {
+			return "", false
+		}
+		u.Fragment = ""
+		return u.String(), true
+	}
+
+	var links []string
+	var forms []crawlerForm
+	var form *crawlerForm
+	for _, t := range scanTags(string(b)) {
+		switch {
+		case t.name == "form" && t.closing:
+			if form != nil {
+				forms = append(forms, *form)
+				form = nil
+			}
+		case t.name == "form":
+			// A missing action submits back to the page itself
+			action, ok := resolve(t.attrs["action"])
+			if !ok && t.attrs["action"] == "" {
+				action, ok = pageURL, true
+			}
+			if ok {
+				form = &crawlerForm{Method: strings.ToUpper(t.attrs["method"]), Action: action, Enctype: t.attrs["enctype"]}
+			}
+		case t.closing:
+		case t.name == "input" || t.name == "select" || t.name == "textarea" || t.name == "button":
+			if form != nil && t.attrs["name"] != "" {
+				form.Parameters = append(form.Parameters, t.attrs["name"])
+			}
+		case t.name == "a" || t.name == "area":
+			if link, ok := resolve(t.attrs["href"]); ok {
+				links = append(links, link)
+			}
+		case t.name == "iframe" || t.name == "frame":
+			if link, ok := resolve(t.attrs["src"]); ok {
+				links = append(links, link)
+			}
+		}
+	}
+	if form != nil {
+		forms = append(forms, *form)
+	}
+	return links, forms
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// placeholderValue is the value given to parameters kxss invents itself,
+// such as crawled form fields or wordlist parameters, so that their
+// reflection can be detected like any other value.
+const placeholderValue = "kxss1337"
+
+// crawlerRecord covers one line of katana -jsonl output as well as the
+// flatter hakrawler -json records.
+type crawlerRecord struct {
+	Request struct {
+		Method   string `json:"method"`
+		Endpoint string `json:"endpoint"`
+	} `json:"request"`
+	Response struct {
+		Forms []crawlerForm `json:"forms"`
+	} `json:"response"`
+
+	// hakrawler
+	URL string `json:"URL"`
+}
+
+type crawlerForm struct {
+	Method     string   `json:"method"`
+	Action     string   `json:"action"`
+	Enctype    string   `json:"enctype"`
+	Parameters []string `json:"parameters"`
+}
+
+// readCrawlerChecks turns crawler JSONL into checks: one for every
+// endpoint that has query parameters and one for every discovered form.
+func readCrawlerChecks(r io.Reader, fn func(paramCheck)) error {
+	seen := make(map[string]bool)
+	emit := func(c paramCheck) {
+		key := c.url
+		if c.tmpl != nil {
+			key = c.tmpl.Method + " " + c.url + " " + c.tmpl.Body
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		fn(c)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // katana lines embed whole response bodies
+	for scanner.Scan() {
+		var rec crawlerRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+
+		endpoint, method := rec.Request.Endpoint, rec.Request.Method
+		if endpoint == "" {
+			endpoint = rec.URL
+		}
+		if u, err := url.Parse(endpoint); err == nil && u.RawQuery != "" {
+			c := paramCheck{url: endpoint}
+			if method != "" && method != "GET" {
+				c.tmpl = &requestTemplate{Method: method, Header: make(http.Header)}
+			}
+			emit(c)
+		}
+
+		for _, form := range rec.Response.Forms {
+			if c, ok := formCheck(form); ok {
+				emit(c)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// formCheck builds a check for a crawled form, filling every field with
+// placeholderValue. Forms without fields, or with an encoding other than
+// urlencoded, are skipped.
+func formCheck(form crawlerForm) (paramCheck, bool) {
+	if form.Action == "" || len(form.Parameters) == 0 {
+		return paramCheck{}, false
+	}
+	u, err := url.Parse(form.Action)
+	if err != nil {
+		return paramCheck{}, false
+	}
+
+	values := url.Values{}
+	for _, p := range form.Parameters {
+		values.Set(p, placeholderValue)
+	}
+
+	if !strings.EqualFold(form.Method, "POST") {
+		qs := u.Query()
+		for k, vv := range values {
+			qs[k] = vv
+		}
+		u.RawQuery = qs.Encode()
+		return paramCheck{url: u.String()}, true
+	}
+
+	if form.Enctype != "" && form.Enctype != "application/x-www-form-urlencoded" {
+		return paramCheck{}, false
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return paramCheck{url: u.String(), tmpl: &requestTemplate{
+		Method: "POST",
+		Header: header,
+		Body:   values.Encode(),
+	}}, true
+}
@@ -0,0 +1,49 @@
+package main
+
+// crlfHeader is the response header a successful CRLF injection adds.
+const (
+	crlfHeader = "X-Kxss-Crlf"
+	crlfMarker = reflectionCanary
+)
+
+// crlfPayloads end the current header line in the ways servers are known
+// to accept: CRLF, a bare LF, CRLF encoded once more for applications that
+// decode again, and the U+560D U+560A pair some servers cut down to CR LF.
+var crlfPayloads = []string{
+	"\r\n" + crlfHeader + ":" + crlfMarker,
+	"\n" + crlfHeader + ":" + crlfMarker,
+	"%0d%0a" + crlfHeader + ":" + crlfMarker,
+	"嘍嘊" + crlfHeader + ":" + crlfMarker,
+}
+
+var crlfCheck = injectionCheck{
+	label: "CRLF Injection",
+	found: func(r Result) bool { return r.CRLFInjection },
+	test:  testCRLF,
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, crlfCheck)
+}
+
+// testCRLF appends each of crlfPayloads to c's parameter and looks for the
+// header it would add in the response.
+func testCRLF(c paramCheck, r *Result) error {
+	// net/http refuses to send header values with line breaks
+	if c.loc == locHeader {
+		return nil
+	}
+	for _, p := range crlfPayloads {
+		resp, err := sendInjected(c, p)
+		if err != nil {
+			return err
+		}
+		if resp.header.Get(crlfHeader) == crlfMarker {
+			r.CRLFInjection = true
+			r.Payload = p
+			r.Curl = resp.curl
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultCSRFFields are the form fields, meta tags and headers commonly
+// used for anti-CSRF tokens by web frameworks.
+var defaultCSRFFields = []string{
+	"csrf", "_csrf", "csrf_token", "csrftoken", "csrf-token", "csrfmiddlewaretoken",
+	"authenticity_token", "__requestverificationtoken", "_token", "xsrf_token",
+	"x-csrf-token", "x-xsrf-token",
+}
+
+// csrfRefresher fetches a fresh anti-CSRF token before every request with
+// a form body, since a token copied from the original request is usually
+// single-use or bound to a session that has moved on.
+type csrfRefresher struct {
+	fields  map[string]bool // lower-cased field and header names
+	pattern *regexp.Regexp  // optional; its first group is the token
+	pageURL string          // page holding the token; "" for the target itself
+}
+
+// csrf is set by -csrf; nil leaves form bodies as they are.
+var csrf *csrfRefresher
+
+func newCSRFRefresher(fields []string, pattern, pageURL string) (*csrfRefresher, error) {
+	if len(fields) == 0 {
+		fields = defaultCSRFFields
+	}
+	r := &csrfRefresher{fields: make(map[string]bool), pageURL: pageURL}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("-csrf-regex needs a group capturing the token")
+		}
+		r.pattern = re
+	}
+	return r, nil
+}
+
+// isToken reports whether name is an anti-CSRF field, which is then not
+// worth testing for reflection.
+func (r *csrfRefresher) isToken(name string) bool {
+	return r != nil && r.fields[strings.ToLower(name)]
+}
+
+// refresh fetches the form page of c and returns body and c's template
+// headers with every token field updated. The tested parameter is never
+// replaced.
+func (r *csrfRefresher) refresh(c paramCheck, body string) (string, http.Header, error) {
+	header := c.tmpl.Header
+	if r == nil {
+		return body, header, nil
+	}
+	page := r.pageURL
+	if page == "" {
+		page = c.url
+	}
+	resp, err := doRequestWithRetries("GET", page, header, "", 3)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetching CSRF token: %v", err)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	resp.Body.Close()
+	if err != nil {
+		return "", nil, fmt.Errorf("fetching CSRF token: %v", err)
+	}
+	tokens, first := r.extract(string(b))
+	if first == "" {
+		return body, header, nil
+	}
+
+	form, err := url.ParseQuery(body)
+	if err != nil {
+		return "", nil, err
+	}
+	changed := false
+	for name := range form {
+		if name == c.param || !r.isToken(name) {
+			continue
+		}
+		form.Set(name, tokenFor(tokens, first, name))
+		changed = true
+	}
+	if changed {
+		body = form.Encode()
+	}
+
+	header = header.Clone()
+	for name := range header {
+		if r.isToken(name) {
+			header.Set(name, tokenFor(tokens, first, name))
+		}
+	}
+	return body, header, nil
+}
+
+// extract finds tokens in page: the capture of -csrf-regex when given,
+// otherwise the values of hidden inputs and meta tags named like a token
+// field. first is the first token found.
+func (r *csrfRefresher) extract(page string) (map[string]string, string) {
+	tokens := make(map[string]string)
+	if r.pattern != nil {
+		if m := r.pattern.FindStringSubmatch(page); m != nil {
+			return tokens, m[1]
+		}
+		return tokens, ""
+	}
+	first := ""
+	for _, t := range scanTags(page) {
+		var name, value string
+		switch t.name {
+		case "input":
+			name, value = t.attrs["name"], t.attrs["value"]
+		case "meta":
+			name, value = t.attrs["name"], t.attrs["content"]
+		default:
+			continue
+		}
+		if value == "" || !r.isToken(name) {
+			continue
+		}
+		name = strings.ToLower(name)
+		if _, ok := tokens[name]; !ok {
+			tokens[name] = value
+		}
+		if first == "" {
+			first = value
+		}
+	}
+	return tokens, first
+}
+
+// tokenFor picks the token extracted under name, falling back to the
+// first one found, as a header such as X-CSRF-Token usually carries the
+// value of a differently named meta tag.
+func tokenFor(tokens map[string]string, first, name string) string {
+	if v, ok := tokens[strings.ToLower(name)]; ok {
+		return v
+	}
+	return first
+}
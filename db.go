@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sqlIdentifier limits table names taken from flags to plain identifiers,
+// since they have to be interpolated into statements.
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// openDB opens dsn with the driver implied by its form: postgres:// and
+// postgresql:// URLs use Postgres, anything else is a SQLite path (an
+// optional sqlite:// prefix is stripped).
+func openDB(dsn string) (*sql.DB, string, error) {
+	driver := "sqlite"
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		driver = "postgres"
+	case strings.HasPrefix(dsn, "sqlite://"):
+		dsn = strings.TrimPrefix(dsn, "sqlite://")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, "", err
+	}
+	return db, driver, nil
+}
+
+// readDBURLs runs query and returns the first column of every row.
+func readDBURLs(db *sql.DB, query string) ([]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// dbSink writes each result as a row of a results table.
+type dbSink struct {
+	db     *sql.DB
+	insert string
+}
+
+func newDBSink(db *sql.DB, driver, table string) (*dbSink, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		url TEXT NOT NULL,
+		param TEXT NOT NULL,
+		location TEXT NOT NULL,
+		unfiltered TEXT NOT NULL,
+		sql_injection BOOLEAN NOT NULL,
+		kinds TEXT NOT NULL DEFAULT '',
+		found_at TIMESTAMP NOT NULL
+	)`, table)
+	if _, err := db.Exec(create); err != nil {
+		return nil, err
+	}
+	if err := addKindsColumn(db, table); err != nil {
+		return nil, err
+	}
+
+	placeholders := "?, ?, ?, ?, ?, ?, ?"
+	if driver == "postgres" {
+		placeholders = "$1, $2, $3, $4, $5, $6, $7"
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (url, param, location, unfiltered, sql_injection, kinds, found_at) VALUES (%s)", table, placeholders)
+	return &dbSink{db: db, insert: insert}, nil
+}
+
+// addKindsColumn adds the kinds column to a table created before it
+// existed.
+func addKindsColumn(db *sql.DB, table string) error {
+	if _, err := db.Exec(fmt.Sprintf("SELECT kinds FROM %s LIMIT 0", table)); err == nil {
+		return nil
+	}
+	_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN kinds TEXT NOT NULL DEFAULT ''", table))
+	return err
+}
+
+// resultKinds is the kinds column: the findingLabels of r.
+func resultKinds(r Result) string {
+	return strings.Join(findingLabels(r), "; ")
+}
+
+func (s *dbSink) write(r Result) error {
+	_, err := s.db.Exec(s.insert, r.URL, r.Param, string(r.Location), strings.Join(r.Unfiltered, " "), r.SQLInjection, resultKinds(r), time.Now().UTC())
+	return err
+}
+
+func (s *dbSink) close() error {
+	return s.db.Close()
+}
+
+// scanDBSchema is the layout used by -format sqlite. Every run adds a row
+// to scans, and findings and errors point back to it, so results from
+// many runs can live in one file and be compared with plain SQL.
+const scanDBSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id TEXT PRIMARY KEY,
+	args TEXT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP,
+	findings INTEGER NOT NULL DEFAULT 0,
+	errors INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS findings (
+	scan_id TEXT NOT NULL REFERENCES scans(id),
+	url TEXT NOT NULL,
+	host TEXT NOT NULL,
+	param TEXT NOT NULL,
+	location TEXT NOT NULL,
+	unfiltered TEXT NOT NULL,
+	sql_injection BOOLEAN NOT NULL,
+	kinds TEXT NOT NULL DEFAULT '',
+	severity TEXT NOT NULL,
+	found_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS findings_host ON findings (host);
+CREATE INDEX IF NOT EXISTS findings_param ON findings (param);
+CREATE INDEX IF NOT EXISTS findings_scan ON findings (scan_id);
+CREATE TABLE IF NOT EXISTS errors (
+	scan_id TEXT NOT NULL REFERENCES scans(id),
+	url TEXT NOT NULL,
+	param TEXT NOT NULL,
+	message TEXT NOT NULL,
+	at TIMESTAMP NOT NULL
+);
+`
+
+// scanDBSink stores findings, request errors and the run itself in a
+// SQLite database.
+type scanDBSink struct {
+	db       *sql.DB
+	scanID   string
+	mu       sync.Mutex
+	findings int
+	errors   int
+}
+
+func newScanDBSink(path, scanID string, args []string) (*scanDBSink, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(scanDBSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addKindsColumn(db, "findings"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec("INSERT INTO scans (id, args, started_at) VALUES (?, ?, ?)", scanID, strings.Join(args, " "), time.Now().UTC())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &scanDBSink{db: db, scanID: scanID}, nil
+}
+
+func (s *scanDBSink) write(r Result) error {
+	s.mu.Lock()
+	s.findings++
+	s.mu.Unlock()
+	_, err := s.db.Exec("INSERT INTO findings (scan_id, url, host, param, location, unfiltered, sql_injection, kinds, severity, found_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		s.scanID, r.URL, resultHost(r), r.Param, string(r.Location), strings.Join(r.Unfiltered, " "), r.SQLInjection, resultKinds(r), r.Severity, time.Now().UTC())
+	return err
+}
+
+// writeError records a request that failed while testing url.
+func (s *scanDBSink) writeError(url, param string, scanErr error) error {
+	s.mu.Lock()
+	s.errors++
+	s.mu.Unlock()
+	_, err := s.db.Exec("INSERT INTO errors (scan_id, url, param, message, at) VALUES (?, ?, ?, ?, ?)",
+		s.scanID, url, param, scanErr.Error(), time.Now().UTC())
+	return err
+}
+
+func (s *scanDBSink) close() error {
+	_, err := s.db.Exec("UPDATE scans SET finished_at = ?, findings = ?, errors = ? WHERE id = ?",
+		time.Now().UTC(), s.findings, s.errors, s.scanID)
+	if cerr := s.db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !nodb
+
+package main
+
+// Database drivers for -db-dsn. Build with -tags nodb to leave them out.
+import (
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
@@ -0,0 +1,66 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dbErrorSignature is an entry of a -db-errors file: the messages that
+// give away engine.
+type dbErrorSignature struct {
+	Engine   string   `yaml:"engine"`
+	Patterns []string `yaml:"patterns"`
+}
+
+//go:embed dberrors.yaml
+var defaultDBErrors []byte
+
+// dbErrorPatterns are checked in order against every probe response; set
+// from -db-errors, or the embedded dberrors.yaml.
+var dbErrorPatterns = mustParseDBErrors(defaultDBErrors)
+
+func parseDBErrors(raw []byte) ([]dbErrorSignature, error) {
+	var sigs []dbErrorSignature
+	if err := yaml.Unmarshal(raw, &sigs); err != nil {
+		return nil, err
+	}
+	for _, s := range sigs {
+		if s.Engine == "" || len(s.Patterns) == 0 {
+			return nil, fmt.Errorf("every entry needs an engine and patterns")
+		}
+	}
+	return sigs, nil
+}
+
+func mustParseDBErrors(raw []byte) []dbErrorSignature {
+	sigs, err := parseDBErrors(raw)
+	if err != nil {
+		panic("dberrors.yaml: " + err.Error())
+	}
+	return sigs
+}
+
+func loadDBErrors(path string) ([]dbErrorSignature, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDBErrors(raw)
+}
+
+// matchDBError returns the engine of the first of dbErrorPatterns found
+// in body, or "".
+func matchDBError(body string) string {
+	for _, s := range dbErrorPatterns {
+		for _, pattern := range s.Patterns {
+			if strings.Contains(body, pattern) {
+				return s.Engine
+			}
+		}
+	}
+	return ""
+}
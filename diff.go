@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Statuses of a finding when compared against an earlier scan. Findings
+// that disappeared are reported with statusFixed.
+const (
+	statusNew        = "new"
+	statusPersisting = "persisting"
+)
+
+// findingKey identifies the same finding across scans. The injection
+// checks that hit are part of it, as they are reported apart from the
+// reflection in the same parameter, and a new kind of flaw in a known
+// parameter is a new finding.
+type findingKey struct {
+	url    string
+	param  string
+	loc    paramLocation
+	checks string
+}
+
+func resultKey(r Result) findingKey {
+	loc := r.Location
+	if loc == "" {
+		loc = locQuery
+	}
+	var checks []string
+	for _, ic := range foundChecks(r) {
+		checks = append(checks, ic.label)
+	}
+	return findingKey{r.URL, r.Param, loc, strings.Join(checks, "\x00")}
+}
+
+// diffResults compares two scans: every finding of current is new or
+// persisting, followed by the findings of previous that are gone.
+func diffResults(previous, current []Result) []verifiedResult {
+	before := make(map[findingKey]bool, len(previous))
+	for _, r := range previous {
+		before[resultKey(r)] = true
+	}
+	now := make(map[findingKey]bool, len(current))
+
+	out := make([]verifiedResult, 0, len(current))
+	for _, r := range current {
+		k := resultKey(r)
+		if now[k] {
+			continue
+		}
+		now[k] = true
+		status := statusNew
+		if before[k] {
+			status = statusPersisting
+		}
+		out = append(out, verifiedResult{Result: r, Status: status})
+	}
+	for _, r := range previous {
+		k := resultKey(r)
+		if !now[k] {
+			now[k] = true
+			out = append(out, verifiedResult{Result: r, Status: statusFixed})
+		}
+	}
+	return out
+}
+
+// runDiff implements "kxss diff old.json new.json".
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var outputFile string
+	var jsonOutput bool
+	fs.StringVar(&outputFile, "o", "", "file to write output to")
+	fs.BoolVar(&jsonOutput, "j", false, "output results in JSON format")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: kxss diff [-j] [-o file] old.json new.json\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	previous, err := readResults(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading results %s: %s\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	current, err := readResults(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading results %s: %s\n", fs.Arg(1), err)
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating output file %s: %s\n", outputFile, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	for _, v := range diffResults(previous, current) {
+		writeVerified(out, v, jsonOutput)
+	}
+}
+
+// baselineSet holds the findings of an earlier scan passed with -baseline
+// and remembers which of them were seen again.
+type baselineSet struct {
+	path    string
+	results []Result
+	known   map[findingKey]bool
+	seen    map[findingKey]bool
+	fresh   int
+}
+
+func loadBaseline(path string) (*baselineSet, error) {
+	results, err := readResults(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &baselineSet{
+		path:    path,
+		results: results,
+		known:   make(map[findingKey]bool, len(results)),
+		seen:    make(map[findingKey]bool),
+	}
+	for _, r := range results {
+		b.known[resultKey(r)] = true
+	}
+	return b, nil
+}
+
+// isNew records r and reports whether it was absent from the baseline.
+// A nil baseline treats every finding as new. Callers serialize access.
+func (b *baselineSet) isNew(r Result) bool {
+	if b == nil {
+		return true
+	}
+	k := resultKey(r)
+	if b.known[k] {
+		b.seen[k] = true
+		return false
+	}
+	b.fresh++
+	return true
+}
+
+// report writes the new/persisting/fixed counts and the fixed findings.
+func (b *baselineSet) report(w io.Writer) {
+	if b == nil {
+		return
+	}
+	fixed := make([]Result, 0)
+	for _, r := range b.results {
+		if !b.seen[resultKey(r)] {
+			fixed = append(fixed, r)
+		}
+	}
+	fmt.Fprintf(w, "baseline %s: %d new, %d persisting, %d fixed\n", b.path, b.fresh, len(b.seen), len(fixed))
+	for _, r := range fixed {
+		writeVerified(w, verifiedResult{Result: r, Status: statusFixed}, false)
+	}
+}
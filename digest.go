@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestChallenge is the parsed Digest WWW-Authenticate header of a 401.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+	nc        uint32
+}
+
+// digestAuth answers RFC 7616 Digest challenges with the -digest-auth
+// credentials. The last challenge per host is reused with an increasing
+// nonce count so only the first request, or one after the nonce expires,
+// costs an extra round trip.
+type digestAuth struct {
+	user       string
+	pass       string
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+// digest is set by -digest-auth; nil sends requests unauthenticated.
+var digest *digestAuth
+
+func newDigestAuth(creds string) (*digestAuth, error) {
+	user, pass, ok := strings.Cut(creds, ":")
+	if !ok {
+		return nil, fmt.Errorf("credentials must look like user:password")
+	}
+	return &digestAuth{user: user, pass: pass, challenges: make(map[string]*digestChallenge)}, nil
+}
+
+// do sends req, answering a Digest challenge once. body is the request
+// body, needed to send the request a second time.
+func (d *digestAuth) do(req *http.Request, body string) (*http.Response, error) {
+	if d == nil {
+		return sendRequest(req)
+	}
+	d.authorize(req)
+	resp, err := sendRequest(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	ch := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if ch == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	d.mu.Lock()
+	d.challenges[req.URL.Host] = ch
+	d.mu.Unlock()
+
+	retry := req.Clone(req.Context())
+	retry.Body = http.NoBody
+	if body != "" {
+		retry.Body = io.NopCloser(strings.NewReader(body))
+	}
+	d.authorize(retry)
+	return sendRequest(retry)
+}
+
+// authorize adds an Authorization header answering the cached challenge
+// for req's host, if there is one.
+func (d *digestAuth) authorize(req *http.Request) {
+	d.mu.Lock()
+	ch := d.challenges[req.URL.Host]
+	if ch == nil {
+		d.mu.Unlock()
+		return
+	}
+	ch.nc++
+	c := *ch
+	d.mu.Unlock()
+
+	newHash := md5.New
+	alg := strings.ToUpper(c.algorithm)
+	if strings.HasPrefix(alg, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		return hashHex(newHash(), s)
+	}
+
+	cnonceBytes := make([]byte, 8)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := fmt.Sprintf("%08x", c.nc)
+	uri := req.URL.RequestURI()
+
+	ha1 := h(d.user + ":" + c.realm + ":" + d.pass)
+	if strings.HasSuffix(alg, "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+
+	var response string
+	if c.qop != "" {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	username := d.user
+	if c.userhash {
+		username = h(d.user + ":" + c.realm)
+	}
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, c.realm),
+		fmt.Sprintf(`nonce="%s"`, c.nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if c.algorithm != "" {
+		parts = append(parts, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, c.opaque))
+	}
+	if c.qop != "" {
+		parts = append(parts, "qop=auth", "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if c.userhash {
+		parts = append(parts, "userhash=true")
+	}
+	req.Header.Set("Authorization", "Digest "+strings.Join(parts, ", "))
+}
+
+func hashHex(h hash.Hash, s string) string {
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseDigestChallenge picks the strongest Digest challenge kxss can
+// answer (SHA-256 over MD5, qop=auth or none) from WWW-Authenticate
+// values, or returns nil.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	for _, v := range values {
+		rest, ok := cutPrefixFold(strings.TrimSpace(v), "Digest ")
+		if !ok {
+			continue
+		}
+		params := parseAuthParams(rest)
+		ch := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+		switch alg := strings.ToUpper(ch.algorithm); alg {
+		case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		default:
+			continue
+		}
+		if qop := params["qop"]; qop != "" {
+			supported := false
+			for _, q := range strings.Split(qop, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					supported = true
+				}
+			}
+			if !supported {
+				continue
+			}
+			ch.qop = "auth"
+		}
+		if ch.nonce == "" {
+			continue
+		}
+		if best == nil || strings.HasPrefix(strings.ToUpper(ch.algorithm), "SHA-256") {
+			best = ch
+		}
+	}
+	return best
+}
+
+// parseAuthParams splits comma-separated key=value and key="quoted value"
+// pairs, allowing commas inside quotes.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value = b.String()
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+	}
+	return params
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// domTabs is how many browser tabs -dom loads pages in at once.
+const domTabs = 4
+
+// domPayload is sent in location.search and location.hash: every special
+// character between two canaries, so that a sink it reaches shows which
+// of them the page's own code leaves alone.
+var domPayload = reflectionCanary + strings.Join(specialChars, "") + reflectionCanary
+
+// domHookScript runs in every page before its own scripts and records the
+// values reaching HTML and script sinks that contain the canary. Only the
+// canary and what follows it is kept.
+var domHookScript = `(() => {
+	const canary = ` + "`" + reflectionCanary + "`" + `;
+	const hits = window.__kxssSinks = [];
+	const record = (sink, args) => {
+		const v = Array.prototype.filter.call(args, a => typeof a === "string").join("");
+		const i = v.indexOf(canary);
+		if (i >= 0) hits.push({sink: sink, value: v.slice(i, i + 2 * canary.length + 64)});
+	};
+	const setter = (proto, prop) => {
+		const d = Object.getOwnPropertyDescriptor(proto, prop);
+		if (!d || !d.set) return;
+		Object.defineProperty(proto, prop, Object.assign({}, d, {set(v) { record(prop, [v]); return d.set.call(this, v); }}));
+	};
+	const method = (obj, name, sink) => {
+		const f = obj && obj[name];
+		if (typeof f !== "function") return;
+		obj[name] = function() { record(sink, arguments); return f.apply(this, arguments); };
+	};
+	setter(Element.prototype, "innerHTML");
+	setter(Element.prototype, "outerHTML");
+	setter(HTMLIFrameElement.prototype, "srcdoc");
+	method(Element.prototype, "insertAdjacentHTML", "insertAdjacentHTML");
+	method(Document.prototype, "write", "document.write");
+	method(Document.prototype, "writeln", "document.writeln");
+	method(Range.prototype, "createContextualFragment", "createContextualFragment");
+	method(window, "eval", "eval");
+	method(window, "setTimeout", "setTimeout");
+	method(window, "setInterval", "setInterval");
+	const F = window.Function;
+	window.Function = new Proxy(F, {
+		apply(t, self, args) { record("Function", args); return Reflect.apply(t, self, args); },
+		construct(t, args) { record("Function", args); return Reflect.construct(t, args); },
+	});
+})();`
+
+// domHit is a sink the canary reached, with the value passed to it from
+// the canary on.
+type domHit struct {
+	Sink  string `json:"sink"`
+	Value string `json:"value"`
+}
+
+// domScanner loads pages in a headless browser with domHookScript in
+// place and returns the sinks the canary reached.
+type domScanner interface {
+	load(urlStr string, header http.Header) ([]domHit, error)
+	close()
+}
+
+// domOptions configure the browser behind -dom.
+type domOptions struct {
+	proxy    string
+	insecure bool
+	timeout  time.Duration
+}
+
+// newDOMScanner starts a headless browser for -dom. It is nil in builds
+// without chromedp.
+var newDOMScanner func(opts domOptions) (domScanner, error)
+
+// domProbe is one page load: urlStr with the payload in a single query
+// parameter, or in the fragment when param is "location.hash".
+type domProbe struct {
+	param  string
+	urlStr string
+}
+
+// domProbes returns the loads -dom makes for urlStr: one per query
+// parameter and one with the payload in the fragment.
+func domProbes(urlStr, payload string) []domProbe {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil
+	}
+	u.Fragment = ""
+	qs := u.Query()
+	names := make([]string, 0, len(qs))
+	for name := range qs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	probes := make([]domProbe, 0, len(names)+1)
+	for _, name := range names {
+		pu := *u
+		q := u.Query()
+		q.Set(name, payload)
+		pu.RawQuery = q.Encode()
+		probes = append(probes, domProbe{param: name, urlStr: pu.String()})
+	}
+	pu := *u
+	pu.Fragment = payload
+	probes = append(probes, domProbe{param: "location.hash", urlStr: pu.String()})
+	return probes
+}
+
+// domStage returns a worker that passes every check on and, for GET
+// requests, loads its URL in the browser behind s once per domProbe,
+// handing a Result for each sink the canary reaches to report.
+func domStage(s domScanner, report func(Result)) workerFunc {
+	return func(c paramCheck, output chan paramCheck) {
+		defer func() { output <- c }()
+		if c.tmpl != nil && (c.tmpl.Method != "GET" || c.tmpl.Body != "") {
+			return
+		}
+		for _, p := range domProbes(c.url, domPayload) {
+			u, err := url.Parse(p.urlStr)
+			if err != nil {
+				continue
+			}
+			var header http.Header
+			if c.tmpl != nil {
+				header = c.tmpl.Header
+			}
+			pause()
+			hostRateLimit.wait(u.Host)
+			rateLimit.wait()
+			hits, err := s.load(p.urlStr, requestHeader(u, header))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error loading %s in the browser: %s\n", p.urlStr, err)
+				return
+			}
+			seen := make(map[string]bool)
+			for _, h := range hits {
+				if seen[h.Sink] {
+					continue
+				}
+				seen[h.Sink] = true
+				if r := domResult(c.url, p, h); len(r.Unfiltered) > 0 {
+					report(r)
+				}
+			}
+		}
+	}
+}
+
+// domResult turns a sink hit into a finding. The characters that count as
+// unfiltered are those still present between the two canaries.
+func domResult(urlStr string, p domProbe, h domHit) Result {
+	tail, _, _ := strings.Cut(strings.TrimPrefix(h.Value, reflectionCanary), reflectionCanary)
+	r := Result{
+		URL:        urlStr,
+		Param:      p.param,
+		Location:   locDOM,
+		Sink:       h.Sink,
+		Unfiltered: []string{},
+		Evidence:   h.Value,
+	}
+	for _, char := range specialChars {
+		if strings.Contains(tail, char) {
+			r.Unfiltered = append(r.Unfiltered, char)
+		}
+	}
+	r.Severity = classifySeverity(r)
+	for _, pp := range domProbes(urlStr, pocPayload) {
+		if pp.param == p.param {
+			r.PoC = pp.urlStr
+		}
+	}
+	r.Fingerprint = fingerprint(r)
+	return r
+}
@@ -0,0 +1,81 @@
+//go:build !nochromedp
+
+package main
+
+// Headless Chrome for -dom. Build with -tags nochromedp to leave it out.
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+func init() {
+	newDOMScanner = newChromeScanner
+}
+
+// chromeScanner loads each page in a new tab of one headless Chrome.
+type chromeScanner struct {
+	browser context.Context
+	cancel  func()
+	timeout time.Duration
+}
+
+func newChromeScanner(opts domOptions) (domScanner, error) {
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.DisableGPU)
+	if opts.proxy != "" {
+		allocOpts = append(allocOpts, chromedp.ProxyServer(opts.proxy))
+	}
+	if opts.insecure {
+		allocOpts = append(allocOpts, chromedp.Flag("ignore-certificate-errors", true))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	browser, cancelBrowser := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelBrowser()
+		cancelAlloc()
+	}
+	// Start the browser now so that a missing Chrome is reported up front
+	if err := chromedp.Run(browser); err != nil {
+		cancel()
+		return nil, err
+	}
+	return &chromeScanner{browser: browser, cancel: cancel, timeout: opts.timeout}, nil
+}
+
+func (s *chromeScanner) load(urlStr string, header http.Header) ([]domHit, error) {
+	tab, cancel := chromedp.NewContext(s.browser)
+	defer cancel()
+	if s.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		tab, cancelTimeout = context.WithTimeout(tab, s.timeout)
+		defer cancelTimeout()
+	}
+
+	extra := make(network.Headers, len(header))
+	for name, vv := range header {
+		extra[name] = strings.Join(vv, ", ")
+	}
+	var hits []domHit
+	err := chromedp.Run(tab,
+		network.Enable(),
+		network.SetExtraHTTPHeaders(extra),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(domHookScript).Do(ctx)
+			return err
+		}),
+		chromedp.Navigate(urlStr),
+		// Let timers and handlers set up on load run before reading the hits
+		chromedp.Sleep(time.Second),
+		chromedp.Evaluate(`window.__kxssSinks || []`, &hits),
+	)
+	return hits, err
+}
+
+func (s *chromeScanner) close() {
+	s.cancel()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// esBatchSize is how many findings are buffered before a _bulk request.
+const esBatchSize = 100
+
+// esDocument is a Result as indexed into Elasticsearch, with the fields
+// needed to slice findings across scans in Kibana.
+type esDocument struct {
+	Result
+	Host      string `json:"host"`
+	ScanID    string `json:"scan_id"`
+	Timestamp string `json:"@timestamp"`
+}
+
+// esSink bulk-indexes findings into an Elasticsearch or OpenSearch index.
+// Credentials may be given as user:pass in the base URL.
+type esSink struct {
+	bulkURL string
+	index   string
+	scanID  string
+	client  *http.Client
+	buf     bytes.Buffer
+	pending int
+}
+
+func newESSink(baseURL, index, scanID string) *esSink {
+	return &esSink{
+		bulkURL: strings.TrimRight(baseURL, "/") + "/_bulk",
+		index:   index,
+		scanID:  scanID,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *esSink) write(r Result) error {
+	action, err := json.Marshal(map[string]any{"index": map[string]string{"_index": s.index}})
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(esDocument{
+		Result:    r,
+		Host:      resultHost(r),
+		ScanID:    s.scanID,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	s.buf.Write(action)
+	s.buf.WriteByte('\n')
+	s.buf.Write(doc)
+	s.buf.WriteByte('\n')
+	s.pending++
+	if s.pending >= esBatchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *esSink) flush() error {
+	if s.pending == 0 {
+		return nil
+	}
+	defer func() {
+		s.buf.Reset()
+		s.pending = 0
+	}()
+
+	req, err := http.NewRequest("POST", s.bulkURL, bytes.NewReader(s.buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bulk request returned %s", resp.Status)
+	}
+
+	// A 200 can still carry per-document failures
+	var bulk struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &bulk); err != nil {
+		return err
+	}
+	if bulk.Errors {
+		return fmt.Errorf("some of %d documents were not indexed", s.pending)
+	}
+	return nil
+}
+
+func (s *esSink) close() error {
+	return s.flush()
+}
+
+// newScanID returns a random identifier for tagging everything a single
+// run produces.
+func newScanID() string {
+	return newUUID()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// probeEncodings is set by -encoded-probes to retry filtered characters
+// in pre-encoded forms.
+var probeEncodings bool
+
+// charEncoding is a way of pre-encoding a probe character that an
+// application might decode again before writing it out. A raw encoding
+// goes into query strings and form bodies as is instead of being
+// percent-encoded once more.
+type charEncoding struct {
+	name   string
+	raw    bool
+	encode func(char string) string
+}
+
+// charEncodings are tried in order on characters that did not come back
+// as sent. Double encoding is decoded once by the server as usual and a
+// second time by the application; triple encoding catches one more. The
+// others catch templates that decode entities, JSON or JavaScript string
+// unescaping, and IIS-style %u decoding.
+var charEncodings = []charEncoding{
+	{"double-url", false, func(char string) string {
+		return url.QueryEscape(char)
+	}},
+	{"triple-url", false, func(char string) string {
+		return url.QueryEscape(url.QueryEscape(char))
+	}},
+	{"html-entity", false, func(char string) string {
+		return fmt.Sprintf("&#x%x;", char[0])
+	}},
+	{"unicode-escape", false, func(char string) string {
+		return fmt.Sprintf(`\u%04x`, char[0])
+	}},
+	{"iis-unicode", true, func(char string) string {
+		return fmt.Sprintf("%%u%04x", char[0])
+	}},
+}
+
+// encodedRequest returns the URL and body for c with the canary and
+// encoded appended to its parameter.
+func encodedRequest(c paramCheck, enc charEncoding, encoded string) (string, string, error) {
+	if !enc.raw {
+		return c.withSuffix(reflectionCanary + encoded)
+	}
+	body := templateBody(c)
+	appendRaw := func(v string) string { return v + reflectionCanary + encoded }
+	switch c.loc {
+	case locQuery:
+		u, err := url.Parse(c.url)
+		if err != nil {
+			return "", "", err
+		}
+		if q, ok := editRawQuery(u.RawQuery, c.param, appendRaw); ok {
+			return replaceRawQuery(c.url, q), body, nil
+		}
+	case locBody:
+		if b, ok := editRawQuery(body, c.param, appendRaw); ok {
+			return c.url, b, nil
+		}
+	case locPath:
+		u, err := editPathSegment(c.url, c.param, appendRaw)
+		return u, body, err
+	}
+	return c.withSuffix(reflectionCanary + encoded)
+}
+
+// probeEncoded sends char after the canary in each of charEncodings and
+// returns the name of the first encoding whose decoded character comes
+// back, or "".
+func probeEncoded(c paramCheck, char string) (string, error) {
+	marker := reflectionCanary + char
+	for _, enc := range charEncodings {
+		testURL, testBody, err := encodedRequest(c, enc, enc.encode(char))
+		if err != nil {
+			return "", err
+		}
+		pc := c.withHeaderSuffix(enc.encode(char))
+		p, err := probeAppend(pc, testURL, testBody, marker)
+		if err != nil {
+			return "", err
+		}
+		if p.reflected {
+			return enc.name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// contentDecoder undoes one Content-Encoding.
+type contentDecoder struct {
+	name   string
+	decode func(io.Reader) (io.ReadCloser, error)
+}
+
+// contentDecoders are the encodings offered in Accept-Encoding, in order
+// of preference. net/http only ever decodes gzip, and only when it chose
+// the Accept-Encoding itself, so kxss asks for and decodes them all so
+// that compressed pages are matched like plain ones.
+var contentDecoders = []contentDecoder{
+	{"gzip", func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}},
+	{"deflate", decodeDeflate},
+	{"zstd", func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}},
+}
+
+// acceptEncoding is the Accept-Encoding sent when the request does not
+// carry one already.
+func acceptEncoding() string {
+	names := make([]string, len(contentDecoders))
+	for i, d := range contentDecoders {
+		names[i] = d.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// decodeDeflate reads "deflate" bodies, which should be zlib streams but
+// are raw DEFLATE from some servers.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(2)
+	if len(head) == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decodedBody closes the decoders stacked on a response body along with
+// the body itself.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// decodeBody replaces resp.Body with its decoded content when every
+// coding in Content-Encoding is known, so reflections can be matched in
+// the plain text. Codings are undone in the reverse of the order they
+// were applied.
+func decodeBody(resp *http.Response) error {
+	codings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	var decoders []contentDecoder
+	for i := len(codings) - 1; i >= 0; i-- {
+		name := strings.ToLower(strings.TrimSpace(codings[i]))
+		if name == "" || name == "identity" {
+			continue
+		}
+		found := false
+		for _, d := range contentDecoders {
+			if d.name == name {
+				decoders = append(decoders, d)
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	if len(decoders) == 0 || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	body := &decodedBody{Reader: resp.Body, closers: []io.Closer{resp.Body}}
+	for _, d := range decoders {
+		rc, err := d.decode(body.Reader)
+		if err != nil {
+			body.Close()
+			return fmt.Errorf("decoding %s response body: %v", d.name, err)
+		}
+		body.Reader = rc
+		body.closers = append(body.closers, rc)
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
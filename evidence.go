@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+// evidenceContext is how many bytes of the response are kept on each side
+// of the reflected value.
+const evidenceContext = 80
+
+// captureLimit bounds how much of a response body -capture stores.
+const captureLimit = 64 * 1024
+
+// captureExchanges is set by -capture to store the full request and
+// response of each finding.
+var captureExchanges bool
+
+// exchange is a captured request and response. The response, headers
+// included, is base64-encoded and cut at captureLimit bytes of body.
+type exchange struct {
+	Request   string `json:"request" xml:"request"`
+	Response  string `json:"response" xml:"response"`
+	Truncated bool   `json:"truncated,omitempty" xml:"truncated,omitempty"`
+}
+
+// reflectionEvidence requests c once more with the canary appended and
+// returns the part of the response around where it is reflected, or ""
+// when it cannot be found, the contexts it is reflected in, and the
+// exchange when capturing.
+func reflectionEvidence(c paramCheck) (string, []string, *exchange) {
+	testURL, testBody, err := c.withSuffix(reflectionCanary)
+	if err != nil {
+		return "", nil, nil
+	}
+	c = c.withHeaderSuffix(reflectionCanary)
+	resp, err := c.send(testURL, testBody)
+	if err != nil {
+		return "", nil, nil
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return "", nil, nil
+	}
+	snippet := snippetAround(string(b), reflectionCanary, evidenceContext)
+	contexts := reflectionContexts(string(b), reflectionCanary)
+	if !captureExchanges {
+		return snippet, contexts, nil
+	}
+	return snippet, contexts, captureExchange(c, testURL, testBody, resp, b)
+}
+
+// captureExchange rebuilds the request kxss sent for c and pairs it with
+// the response head and body.
+func captureExchange(c paramCheck, urlStr, body string, resp *http.Response, respBody []byte) *exchange {
+	method := "GET"
+	var header http.Header
+	if c.tmpl != nil {
+		method = c.tmpl.Method
+		header = c.tmpl.Header
+	}
+	req, err := http.NewRequest(method, urlStr, strings.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	req.Header = requestHeader(req.URL, header)
+	for k, vv := range c.injectedHeader() {
+		req.Header[k] = vv
+	}
+	rawReq, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil
+	}
+	head, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return nil
+	}
+
+	ex := &exchange{Request: string(rawReq)}
+	if len(respBody) > captureLimit {
+		respBody = respBody[:captureLimit]
+		ex.Truncated = true
+	}
+	ex.Response = base64.StdEncoding.EncodeToString(append(bytes.Clone(head), respBody...))
+	return ex
+}
+
+// snippetAround returns s from n bytes before the first occurrence of
+// marker to n bytes after it, trimmed to valid UTF-8.
+func snippetAround(s, marker string, n int) string {
+	i := strings.Index(s, marker)
+	if i < 0 {
+		return ""
+	}
+	start := i - n
+	if start < 0 {
+		start = 0
+	}
+	end := i + len(marker) + n
+	if end > len(s) {
+		end = len(s)
+	}
+	return strings.ToValidUTF8(s[start:end], "")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// paramsPerURL is how many wordlist parameters are packed into a single
+// generated URL; each is still tested on its own.
+const paramsPerURL = 20
+
+// defaultParams is used when base URLs are expanded without a parameter
+// wordlist. These names are reflected often enough to be worth a probe.
+var defaultParams = []string{
+	"q", "s", "search", "query", "keyword", "id", "page", "lang", "name",
+	"url", "redirect", "next", "return", "callback", "ref", "type", "view",
+	"message", "error", "email",
+}
+
+// readWordlist returns the non-empty, non-comment lines of path.
+func readWordlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	out := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, scanner.Err()
+}
+
+// hostBaseURL turns a bare host such as example.com or example.com:8080
+// into a base URL using scheme. Lines that already carry a scheme are
+// kept as they are.
+func hostBaseURL(host, scheme string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return scheme + "://" + strings.TrimRight(host, "/") + "/"
+}
+
+// expandBaseURL generates candidate URLs for base by joining each path
+// and setting the params to placeholderValue, paramsPerURL at a time.
+func expandBaseURL(base string, paths, params []string) []string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil
+	}
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+
+	out := make([]string, 0)
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		for i := 0; i < len(params); i += paramsPerURL {
+			qs := url.Values{}
+			for _, name := range params[i:min(i+paramsPerURL, len(params))] {
+				qs.Set(name, placeholderValue)
+			}
+			cu := *u
+			cu.Path = p
+			cu.RawQuery = qs.Encode()
+			out = append(out, cu.String())
+		}
+	}
+	return out
+}
+
+// templateMarker delimits placeholders such as §FUZZ§ in input URLs.
+const templateMarker = "§"
+
+// expandTemplate replaces every §NAME§ placeholder in line with each of
+// values, yielding the cartesian product when there are several
+// placeholders. Lines without placeholders are returned unchanged.
+func expandTemplate(line string, values []string) []string {
+	parts := strings.Split(line, templateMarker)
+	// parts alternates literal text and placeholder names, so a line with
+	// n complete placeholders splits into 2n+1 parts
+	if len(parts) < 3 || len(parts)%2 == 0 || len(values) == 0 {
+		return []string{line}
+	}
+
+	out := []string{parts[0]}
+	for i := 1; i < len(parts); i += 2 {
+		next := make([]string, 0, len(out)*len(values))
+		for _, prefix := range out {
+			for _, v := range values {
+				next = append(next, prefix+url.QueryEscape(v)+parts[i+1])
+			}
+		}
+		out = next
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ffufReport covers ffuf -of json output. dirsearch JSON reports share
+// the results[].url layout and are read through the same type.
+type ffufReport struct {
+	Results []struct {
+		Input map[string]string `json:"input"`
+		URL   string            `json:"url"`
+	} `json:"results"`
+	Config struct {
+		Method   string            `json:"method"`
+		Headers  map[string]string `json:"headers"`
+		PostData string            `json:"postdata"`
+	} `json:"config"`
+}
+
+// readFfufChecks returns a check for every ffuf hit that has query or
+// body parameters. Fuzz keywords in the configured body and headers are
+// replaced with the input that produced the hit, so a parameter found in
+// a FUZZ position is tested with the request that discovered it.
+func readFfufChecks(path string) ([]paramCheck, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report ffufReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, err
+	}
+
+	out := make([]paramCheck, 0)
+	seen := make(map[string]bool)
+	for _, r := range report.Results {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			continue
+		}
+
+		replacer := ffufReplacer(r.Input)
+		body := replacer.Replace(report.Config.PostData)
+		if u.RawQuery == "" && body == "" {
+			continue
+		}
+		key := r.URL + " " + body
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		c := paramCheck{url: r.URL}
+		method := strings.ToUpper(report.Config.Method)
+		if (method != "" && method != "GET") || body != "" || len(report.Config.Headers) > 0 {
+			header := make(http.Header)
+			for k, v := range report.Config.Headers {
+				if !templateHeaderSkip[http.CanonicalHeaderKey(k)] {
+					header.Set(k, replacer.Replace(v))
+				}
+			}
+			if method == "" {
+				method = "GET"
+			}
+			c.tmpl = &requestTemplate{Method: method, Header: header, Body: body}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// ffufReplacer substitutes each fuzz keyword with its input value. ffuf
+// adds an internal FFUFHASH entry that never appears in requests.
+func ffufReplacer(input map[string]string) *strings.Replacer {
+	pairs := make([]string, 0, 2*len(input))
+	for k, v := range input {
+		if k == "FFUFHASH" {
+			continue
+		}
+		pairs = append(pairs, k, v)
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// harvestClient talks to the archive APIs. It is separate from httpClient
+// so that target headers and scope rules never apply to archive traffic.
+var harvestClient = &http.Client{Timeout: 5 * time.Minute}
+
+// harvestSource pulls historical URLs for a domain and passes each one
+// to fn as it is read.
+type harvestSource struct {
+	name  string
+	fetch func(domain string, fn func(string)) error
+}
+
+var harvestSources = []harvestSource{
+	{"wayback", fetchWaybackURLs},
+	{"commoncrawl", fetchCommonCrawlURLs},
+}
+
+// harvestURLs collects parameterized URLs for domain and its subdomains
+// from every archive source, calling fn once per unique URL. A failing
+// source is reported through onErr and does not stop the others.
+func harvestURLs(domain string, fn func(string), onErr func(source string, err error)) {
+	seen := make(map[string]bool)
+	emit := func(raw string) {
+		u, err := url.Parse(raw)
+		if err != nil || u.RawQuery == "" || seen[raw] {
+			return
+		}
+		seen[raw] = true
+		fn(raw)
+	}
+	for _, src := range harvestSources {
+		if err := src.fetch(domain, emit); err != nil {
+			onErr(src.name, err)
+		}
+	}
+}
+
+func harvestGet(u string) (io.ReadCloser, error) {
+	resp, err := harvestClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, u)
+	}
+	return resp.Body, nil
+}
+
+func fetchWaybackURLs(domain string, fn func(string)) error {
+	q := url.Values{}
+	q.Set("url", "*."+domain+"/*")
+	q.Set("output", "txt")
+	q.Set("fl", "original")
+	q.Set("collapse", "urlkey")
+	q.Set("filter", `original:.*\?.*`)
+	body, err := harvestGet("https://web.archive.org/cdx/search/cdx?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		fn(strings.TrimSpace(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// fetchCommonCrawlURLs queries the most recent Common Crawl index only;
+// older crawls rarely add parameters the Wayback Machine lacks.
+func fetchCommonCrawlURLs(domain string, fn func(string)) error {
+	body, err := harvestGet("https://index.commoncrawl.org/collinfo.json")
+	if err != nil {
+		return err
+	}
+	var indexes []struct {
+		CDXAPI string `json:"cdx-api"`
+	}
+	err = json.NewDecoder(body).Decode(&indexes)
+	body.Close()
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return fmt.Errorf("no Common Crawl indexes listed")
+	}
+
+	q := url.Values{}
+	q.Set("url", "*."+domain+"/*")
+	q.Set("output", "json")
+	q.Set("fl", "url")
+	body, err = harvestGet(indexes[0].CDXAPI + "?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		var rec struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		fn(rec.URL)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultTestHeaders are the request headers -test-headers injects into:
+// the ones most often echoed back in logs, error pages and redirects.
+var defaultTestHeaders = []string{"Referer", "User-Agent", "X-Forwarded-For", "X-Forwarded-Host"}
+
+// testHeaders is set by -test-headers to the headers to test for
+// reflection on every target.
+var testHeaders []string
+
+// checkHeaderReflection sends c's request once per -test-headers header
+// with the canary as its value and returns a check for each header whose
+// canary comes back in an HTML response.
+func checkHeaderReflection(c paramCheck) ([]paramCheck, error) {
+	out := make([]paramCheck, 0)
+	body := ""
+	if c.tmpl != nil {
+		body = c.tmpl.Body
+	}
+	for _, name := range testHeaders {
+		hc := paramCheck{url: c.url, param: http.CanonicalHeaderKey(name), loc: locHeader, tmpl: c.tmpl}
+		resp, err := hc.withHeaderSuffix("").send(c.url, body)
+		if err != nil {
+			return out, err
+		}
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		resp.Body.Close()
+		if err != nil || strings.HasPrefix(resp.Status, "3") {
+			continue
+		}
+		if reflectableType(resp.Header.Get("Content-Type"), locHeader) && strings.Contains(string(b), reflectionCanary) {
+			out = append(out, hc)
+		}
+	}
+	return out, nil
+}
+
+// withHeaderSuffix returns c set to send the canary followed by suffix in
+// its header, when c is a header check. A header check without a value
+// sends its request unchanged, as the baseline does.
+func (c paramCheck) withHeaderSuffix(suffix string) paramCheck {
+	if c.loc == locHeader {
+		c.headerValue = reflectionCanary + suffix
+	}
+	return c
+}
+
+// injectedHeader returns the header a header check sends over whatever
+// the template, -H and the other header options would, or nil. A chunked
+// check asks for chunked transfer encoding the same way.
+func (c paramCheck) injectedHeader() http.Header {
+	var header http.Header
+	if c.loc == locHeader && c.headerValue != "" {
+		header = http.Header{c.param: {c.headerValue}}
+	}
+	if c.chunked {
+		if header == nil {
+			header = make(http.Header)
+		}
+		header.Set("Transfer-Encoding", "chunked")
+	}
+	return header
+}
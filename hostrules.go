@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// hostRule adds headers and cookies to requests for matching hosts. The
+// -host-rules file is a list of them:
+//
+//	# later rules override earlier ones
+//	- hosts: ["*.staging.corp", "staging.corp"]
+//	  headers:
+//	    Authorization: Bearer TOKEN
+//	  cookie: "session=abc"
+type hostRule struct {
+	Hosts   []string          `yaml:"hosts"`
+	Headers map[string]string `yaml:"headers"`
+	Cookie  string            `yaml:"cookie"`
+}
+
+// hostRuleSet is applied in file order, so a later matching rule
+// overrides the headers of an earlier one.
+type hostRuleSet []hostRule
+
+// hostRules is applied to every request; nil adds nothing.
+var hostRules hostRuleSet
+
+func loadHostRules(path string) (hostRuleSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules hostRuleSet
+	if err := yaml.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
+	for i, r := range rules {
+		if len(r.Hosts) == 0 {
+			return nil, fmt.Errorf("rule %d has no hosts", i+1)
+		}
+		for j, h := range r.Hosts {
+			rules[i].Hosts[j] = strings.ToLower(h)
+		}
+	}
+	return rules, nil
+}
+
+// apply sets the headers and adds the cookies of every rule matching the
+// host of u, on top of those already in header.
+func (rs hostRuleSet) apply(u *url.URL, header http.Header) {
+	if len(rs) == 0 || u == nil {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, r := range rs {
+		if !r.matches(host) {
+			continue
+		}
+		for name, value := range r.Headers {
+			header.Set(name, value)
+		}
+		if r.Cookie != "" {
+			cookie := strings.TrimSpace(r.Cookie)
+			if prev := header.Get("Cookie"); prev != "" {
+				cookie = prev + "; " + cookie
+			}
+			header.Set("Cookie", cookie)
+		}
+	}
+}
+
+func (r hostRule) matches(host string) bool {
+	for _, pattern := range r.Hosts {
+		if matchDomain(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hostSplitSink writes each host's findings to its own file in dir, named
+// after the host, using the regular sink for format inside each file.
+// Files are created on a host's first finding.
+type hostSplitSink struct {
+	dir    string
+	format string
+	opts   formatOptions
+	append bool
+	files  map[string]*os.File
+	sinks  map[string]resultSink
+}
+
+func newHostSplitSink(dir, format string, opts formatOptions, appendFiles bool) *hostSplitSink {
+	return &hostSplitSink{
+		dir:    dir,
+		format: format,
+		opts:   opts,
+		append: appendFiles,
+		files:  make(map[string]*os.File),
+		sinks:  make(map[string]resultSink),
+	}
+}
+
+// hostFileName turns a host (possibly with a port or IPv6 brackets) into a
+// safe file name.
+func hostFileName(host string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, host)
+}
+
+func (s *hostSplitSink) write(r Result) error {
+	host := resultHost(r)
+	sink, ok := s.sinks[host]
+	if !ok {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if s.append {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		path := filepath.Join(s.dir, hostFileName(host)+formatExtensions[s.format])
+		file, err := os.OpenFile(path, flags, 0644)
+		if err != nil {
+			return err
+		}
+		sink, err = newFormatSink(s.format, file, s.opts)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		s.files[host] = file
+		s.sinks[host] = sink
+	}
+	return sink.write(r)
+}
+
+func (s *hostSplitSink) close() error {
+	var firstErr error
+	for host, sink := range s.sinks {
+		if err := sink.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := s.files[host].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
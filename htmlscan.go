@@ -0,0 +1,39 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// A deliberately small tag scanner: enough to pull links and form fields
+// out of real-world markup without a full HTML parser.
+var (
+	tagPattern  = regexp.MustCompile(`(?is)<(/?)([a-z][a-z0-9-]*)\b((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	attrPattern = regexp.MustCompile(`(?is)([a-z_:@][-a-z0-9_:.@]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
+
+type htmlTag struct {
+	name    string
+	closing bool
+	attrs   map[string]string
+}
+
+// scanTags returns the start and end tags of body in document order, with
+// lower-cased names and entity-decoded attribute values.
+func scanTags(body string) []htmlTag {
+	matches := tagPattern.FindAllStringSubmatch(body, -1)
+	out := make([]htmlTag, 0, len(matches))
+	for _, m := range matches {
+		t := htmlTag{
+			name:    strings.ToLower(m[2]),
+			closing: m[1] == "/",
+			attrs:   make(map[string]string),
+		}
+		for _, a := range attrPattern.FindAllStringSubmatch(m[3], -1) {
+			t.attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3] + a[4])
+		}
+		out = append(out, t)
+	}
+	return out
+}
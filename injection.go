@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// injectionCheck probes a single parameter for a server-side flaw other
+// than reflection and records what it found on the Result.
+type injectionCheck struct {
+	label  string                              // shown with findings, e.g. "CRLF Injection"
+	found  func(r Result) bool                 // whether r holds a finding of this kind
+	detail func(r Result) string               // optional, e.g. the template engine, shown after the label
+	test   func(c paramCheck, r *Result) error // probes c, filling in r on a hit; nil for out-of-band checks
+}
+
+// injectedResponse is what came back for a request with a payload in the
+// tested parameter.
+type injectedResponse struct {
+	status int
+	header http.Header
+	body   string
+	took   time.Duration // until the response headers arrived
+	curl   string        // reproduces the request
+}
+
+// sendInjected sends c's request with suffix appended to the tested value,
+// as the character checks do, and reads up to 1MB of the response.
+func sendInjected(c paramCheck, suffix string) (injectedResponse, error) {
+	testURL, testBody, err := c.withSuffix(suffix)
+	if err != nil {
+		return injectedResponse{}, err
+	}
+	return sendTest(c.withHeaderSuffix(suffix), testURL, testBody)
+}
+
+// sendReplaced sends c's request with the tested value replaced by value.
+func sendReplaced(c paramCheck, value string) (injectedResponse, error) {
+	testURL, testBody, err := c.withValue(value)
+	if err != nil {
+		return injectedResponse{}, err
+	}
+	if c.loc == locHeader {
+		c.headerValue = value
+	}
+	return sendTest(c, testURL, testBody)
+}
+
+func sendTest(c paramCheck, testURL, testBody string) (injectedResponse, error) {
+	var out injectedResponse
+	start := time.Now()
+	resp, err := c.send(testURL, testBody)
+	if err != nil {
+		return out, err
+	}
+	out.took = time.Since(start)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return out, err
+	}
+	out.status = resp.StatusCode
+	out.header = resp.Header
+	out.body = string(b)
+	out.curl = curlRequest(c, testURL, testBody)
+	return out, nil
+}
+
+// value returns the tested value of c as the input has it, or "" for
+// header checks and values that cannot be read.
+func (c paramCheck) value() string {
+	body := ""
+	if c.tmpl != nil {
+		body = c.tmpl.Body
+	}
+	var v string
+	keep := func(s string) string {
+		v = s
+		return s
+	}
+	switch c.loc {
+	case locQuery:
+		if u, err := url.Parse(c.url); err == nil {
+			v = u.Query().Get(c.param)
+		}
+	case locBody:
+		form, _ := url.ParseQuery(body)
+		v = form.Get(c.param)
+	case locPath:
+		editPathSegment(c.url, c.param, keep)
+		v, _ = url.PathUnescape(v)
+	default:
+		editStructuredBody(c, body, keep)
+	}
+	return v
+}
+
+// injectionChecks are the checks enabled by flags. They run on every
+// parameter, reflected or not.
+var injectionChecks []injectionCheck
+
+// knownInjectionChecks lists every check, enabled or not, so that results
+// read back from a file can still be labelled and re-tested.
+var knownInjectionChecks []injectionCheck
+
+// isFinding reports whether r is worth reporting: something came back
+// unfiltered, a database error showed up, or an injection check hit.
+func (r Result) isFinding() bool {
+	return len(r.Unfiltered) > 0 || r.SQLInjection || len(foundChecks(r)) > 0
+}
+
+// foundChecks returns the injection checks that hit for r.
+func foundChecks(r Result) []injectionCheck {
+	var out []injectionCheck
+	for _, ic := range knownInjectionChecks {
+		if ic.found(r) {
+			out = append(out, ic)
+		}
+	}
+	return out
+}
+
+// findingLabels names the kinds of finding in r besides unfiltered
+// characters, for the text formats.
+func findingLabels(r Result) []string {
+	var out []string
+	if r.SQLInjection && r.Database != "" {
+		out = append(out, "Possible SQL Injection: "+r.Database)
+	} else if r.SQLInjection {
+		out = append(out, "Possible SQL Injection")
+	}
+	for _, ic := range foundChecks(r) {
+		if ic.detail != nil && ic.detail(r) != "" {
+			out = append(out, ic.label+": "+ic.detail(r))
+		} else {
+			out = append(out, ic.label)
+		}
+	}
+	return out
+}
+
+// newResult is an empty Result for c, carrying what it takes to send the
+// request again.
+func newResult(c paramCheck) Result {
+	r := Result{
+		URL:        c.url,
+		Param:      c.param,
+		Location:   c.loc,
+		Unfiltered: []string{},
+	}
+	if c.tmpl != nil {
+		r.Method = c.tmpl.Method
+		r.Body = c.tmpl.Body
+	}
+	return r
+}
+
+// runInjectionChecks runs checks against c and returns a Result for each
+// one that hit, so that every finding keeps its own payload, curl and
+// evidence. Of the checks sharing a label, like the two blind SQL
+// injection techniques, only the first to hit is run.
+func runInjectionChecks(c paramCheck, checks []injectionCheck) []Result {
+	var out []Result
+	hit := make(map[string]bool)
+	for _, ic := range checks {
+		if ic.test == nil || hit[ic.label] {
+			continue
+		}
+		r := newResult(c)
+		if err := ic.test(c, &r); err != nil {
+			fmt.Fprintf(os.Stderr, "error testing url %s with param %s for %s: %s\n", c.url, c.param, ic.label, err)
+		}
+		if !r.isFinding() {
+			continue
+		}
+		hit[ic.label] = true
+		r.Severity = classifySeverity(r)
+		r.Fingerprint = fingerprint(r)
+		out = append(out, r)
+	}
+	return out
+}
+
+// injectionStage returns a worker that passes every check on and runs
+// injectionChecks against each of its parameters, handing findings to
+// report.
+func injectionStage(report func(Result)) workerFunc {
+	return func(c paramCheck, output chan paramCheck) {
+		defer func() { output <- c }()
+		for _, pc := range testedParams(c) {
+			for _, r := range runInjectionChecks(pc, injectionChecks) {
+				report(r)
+			}
+		}
+	}
+}
+
+// injectionDelay is how long time-based probes ask the server to wait.
+const injectionDelay = 7 * time.Second
+
+// timedPayload is a time-based probe; format takes the delay in seconds
+// plus offset, for commands like ping that count something else.
+type timedPayload struct {
+	format string
+	offset int
+}
+
+func (p timedPayload) with(delay time.Duration) string {
+	return fmt.Sprintf(p.format, int(delay/time.Second)+p.offset)
+}
+
+// timingConfirmations is how many times a time-based probe has to come
+// back late, each followed by the same probe asking for no delay coming
+// back on time, before it counts.
+const timingConfirmations = 2
+
+// delayThreshold returns how long a response to c has to take to count
+// as delayed: injectionDelay longer than the slowest of a few unmodified
+// requests, less some slack for the delay being rounded down.
+func delayThreshold(c paramCheck) (time.Duration, error) {
+	var slowest time.Duration
+	for i := 0; i < 3; i++ {
+		base, err := sendInjected(c, "")
+		if err != nil {
+			return 0, err
+		}
+		slowest = max(slowest, base.took)
+	}
+	return slowest + injectionDelay*8/10, nil
+}
+
+// delayedBy reports whether appending p to c's parameter consistently
+// delays the response past threshold. The response is the last delayed
+// one.
+func delayedBy(c paramCheck, p timedPayload, threshold time.Duration) (injectedResponse, bool, error) {
+	var delayed injectedResponse
+	for i := 0; i < timingConfirmations; i++ {
+		resp, err := sendInjected(c, p.with(injectionDelay))
+		if err != nil || resp.took < threshold {
+			return resp, false, err
+		}
+		delayed = resp
+		resp, err = sendInjected(c, p.with(0))
+		if err != nil || resp.took >= threshold {
+			return resp, false, err
+		}
+	}
+	return delayed, true, nil
+}
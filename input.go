@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// inputSource is a named stream of newline-separated URLs.
+type inputSource struct {
+	name string
+	r    io.Reader
+}
+
+// decompress wraps r in a gzip or zstd reader when its leading bytes
+// carry the matching magic number, and returns it unchanged otherwise.
+// The returned close function releases any decoder state.
+func decompress(r io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close() }, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	}
+	return br, func() {}, nil
+}
+
+// openInput opens name, or stdin for "-", with transparent decompression.
+// The returned close function releases both the file and any decoder.
+func openInput(name string) (io.Reader, func(), error) {
+	in := os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		in = file
+	}
+	r, closeFn, err := decompress(in)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	return r, func() {
+		closeFn()
+		in.Close()
+	}, nil
+}
+
+// stdinPiped reports whether stdin is a file or pipe rather than a
+// terminal, i.e. whether URLs are being fed to it.
+func stdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
+}
+
+// followInterval is how long a followed input waits for new data at EOF.
+const followInterval = 500 * time.Millisecond
+
+// followReader turns EOF into a wait for more data, like tail -f, so
+// lines appended to an input are picked up as they arrive.
+type followReader struct {
+	r io.Reader
+}
+
+func (f followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		time.Sleep(followInterval)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonKeys is set by -json-keys: object keys of JSON bodies are tested as
+// well as their string values.
+var jsonKeys bool
+
+// jsonParams lists the string values of a JSON body, and under -json-keys
+// its object keys, named by JSON Pointer (RFC 6901).
+func jsonParams(body string) []bodyParam {
+	v, err := decodeJSON(body)
+	if err != nil {
+		return nil
+	}
+	var out []bodyParam
+	var walk func(v any, ptr string)
+	walk = func(v any, ptr string) {
+		switch v := v.(type) {
+		case string:
+			out = append(out, bodyParam{name: ptr, loc: locJSON, value: v})
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				child := ptr + "/" + escapeJSONPointer(k)
+				if jsonKeys {
+					out = append(out, bodyParam{name: child, loc: locJSONKey, value: k})
+				}
+				walk(v[k], child)
+			}
+		case []any:
+			for i, e := range v {
+				walk(e, ptr+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+	walk(v, "")
+	return out
+}
+
+// editJSON rewrites the string at ptr in body with edit, or with key set
+// the name of the object member at ptr.
+func editJSON(body, ptr string, key bool, edit func(string) string) (string, error) {
+	root, err := decodeJSON(body)
+	if err != nil {
+		return "", err
+	}
+	var tokens []string
+	if ptr != "" {
+		tokens = strings.Split(ptr[1:], "/")
+		for i, t := range tokens {
+			tokens[i] = unescapeJSONPointer(t)
+		}
+	}
+
+	// set replaces the value under the last token in its parent
+	set := func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a string", ptr)
+		}
+		return edit(s), nil
+	}
+	if len(tokens) == 0 {
+		if key {
+			return "", fmt.Errorf("the root of a JSON body has no key")
+		}
+		if root, err = set(root); err != nil {
+			return "", err
+		}
+		return encodeJSON(root)
+	}
+
+	parent := root
+	for _, t := range tokens[:len(tokens)-1] {
+		if parent, err = jsonChild(parent, t); err != nil {
+			return "", fmt.Errorf("%s: %v", ptr, err)
+		}
+	}
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		v, ok := p[last]
+		if !ok {
+			return "", fmt.Errorf("%s: no such member", ptr)
+		}
+		if key {
+			delete(p, last)
+			p[edit(last)] = v
+		} else if p[last], err = set(v); err != nil {
+			return "", err
+		}
+	case []any:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(p) || key {
+			return "", fmt.Errorf("%s: no such element", ptr)
+		}
+		if p[i], err = set(p[i]); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%s: no such member", ptr)
+	}
+	return encodeJSON(root)
+}
+
+func jsonChild(v any, token string) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if c, ok := v[token]; ok {
+			return c, nil
+		}
+	case []any:
+		if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(v) {
+			return v[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no member %q", token)
+}
+
+// decodeJSON keeps numbers as written so that re-encoding does not change
+// them.
+func decodeJSON(body string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// encodeJSON leaves <, > and & alone, as probes must reach the server as
+// the characters themselves rather than \u escapes.
+func encodeJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func escapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func unescapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONParams(t *testing.T) {
+	body := `{"user":{"name":"bob","tags":["a",1,"b"]},"a/b":"x","t~":"y","n":7}`
+	tests := []struct {
+		keys bool
+		want []bodyParam
+	}{
+		{false, []bodyParam{
+			{"/a~1b", locJSON, "x"},
+			{"/t~0", locJSON, "y"},
+			{"/user/name", locJSON, "bob"},
+			{"/user/tags/0", locJSON, "a"},
+			{"/user/tags/2", locJSON, "b"},
+		}},
+		{true, []bodyParam{
+			{"/a~1b", locJSONKey, "a/b"},
+			{"/a~1b", locJSON, "x"},
+			{"/n", locJSONKey, "n"},
+			{"/t~0", locJSONKey, "t~"},
+			{"/t~0", locJSON, "y"},
+			{"/user", locJSONKey, "user"},
+			{"/user/name", locJSONKey, "name"},
+			{"/user/name", locJSON, "bob"},
+			{"/user/tags", locJSONKey, "tags"},
+			{"/user/tags/0", locJSON, "a"},
+			{"/user/tags/2", locJSON, "b"},
+		}},
+	}
+	defer func(old bool) { jsonKeys = old }(jsonKeys)
+	for _, tt := range tests {
+		jsonKeys = tt.keys
+		if got := jsonParams(body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("jsonParams with keys %v = %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+	if got := jsonParams(`{"a":`); got != nil {
+		t.Errorf("jsonParams of a broken body = %v, want nil", got)
+	}
+}
+
+func TestEditJSON(t *testing.T) {
+	suffix := func(s string) string { return s + `"<x>` }
+	tests := []struct {
+		body, ptr string
+		key       bool
+		want      string
+	}{
+		{`{"a":"v","n":1.50}`, "/a", false, `{"a":"v\"<x>","n":1.50}`},
+		{`{"a":{"b":["x","y"]}}`, "/a/b/1", false, `{"a":{"b":["x","y\"<x>"]}}`},
+		{`{"a/b":"v","t~":"w"}`, "/a~1b", false, `{"a/b":"v\"<x>","t~":"w"}`},
+		{`{"a/b":"v","t~":"w"}`, "/t~0", false, `{"a/b":"v","t~":"w\"<x>"}`},
+		{`{"a":"v"}`, "/a", true, `{"a\"<x>":"v"}`},
+		{`"v"`, "", false, `"v\"<x>"`},
+	}
+	for _, tt := range tests {
+		got, err := editJSON(tt.body, tt.ptr, tt.key, suffix)
+		if err != nil {
+			t.Errorf("editJSON(%q, %q, %v): %v", tt.body, tt.ptr, tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("editJSON(%q, %q, %v) = %s, want %s", tt.body, tt.ptr, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestEditJSONErrors(t *testing.T) {
+	tests := []struct {
+		body, ptr string
+		key       bool
+	}{
+		{`{"a":1}`, "/a", false},       // not a string
+		{`{"a":"v"}`, "/b", false},     // no such member
+		{`{"a":["v"]}`, "/a/1", false}, // out of range
+		{`{"a":["v"]}`, "/a/0", true},  // array elements have no key
+		{`{"a":"v"}`, "/a/b/c", false}, // path through a string
+		{`"v"`, "", true},              // the root has no key
+		{`{"a":`, "/a", false},         // broken body
+	}
+	for _, tt := range tests {
+		if got, err := editJSON(tt.body, tt.ptr, tt.key, func(s string) string { return s }); err == nil {
+			t.Errorf("editJSON(%q, %q, %v) = %s, want an error", tt.body, tt.ptr, tt.key, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// jsonlTarget is one line of -input-jsonl input. Cookies may be given
+// either as a header-style string ("a=1; b=2") or as an object.
+type jsonlTarget struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Cookies json.RawMessage   `json:"cookies"`
+	Body    string            `json:"body"`
+}
+
+// readJSONLChecks turns every line of r into a check carrying its own
+// method, headers, cookies and body. Blank lines are skipped; malformed
+// lines are reported with their line number.
+func readJSONLChecks(r io.Reader, fn func(paramCheck)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var t jsonlTarget
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if t.URL == "" {
+			return fmt.Errorf("line %d: missing url", line)
+		}
+		tmpl, err := t.template()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		fn(paramCheck{url: t.URL, tmpl: tmpl})
+	}
+	return scanner.Err()
+}
+
+func (t jsonlTarget) template() (*requestTemplate, error) {
+	header := make(http.Header)
+	for k, v := range t.Headers {
+		if !templateHeaderSkip[http.CanonicalHeaderKey(k)] {
+			header.Set(k, v)
+		}
+	}
+
+	cookie, err := t.cookieHeader()
+	if err != nil {
+		return nil, err
+	}
+	if cookie != "" {
+		if existing := header.Get("Cookie"); existing != "" {
+			cookie = existing + "; " + cookie
+		}
+		header.Set("Cookie", cookie)
+	}
+
+	method := strings.ToUpper(t.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if t.Body != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return &requestTemplate{Method: method, Header: header, Body: t.Body}, nil
+}
+
+func (t jsonlTarget) cookieHeader() (string, error) {
+	if len(t.Cookies) == 0 || string(t.Cookies) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(t.Cookies, &s); err == nil {
+		return s, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(t.Cookies, &m); err != nil {
+		return "", fmt.Errorf("cookies must be a string or an object")
+	}
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(m))
+	for _, k := range names {
+		parts = append(parts, k+"="+m[k])
+	}
+	return strings.Join(parts, "; "), nil
+}
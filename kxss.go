@@ -3,7 +3,7 @@ package main
 import (
 	"bufio"
 	"crypto/tls"
-	"encoding/json"
+	"database/sql"
 	"flag"
 	"fmt"
 	"io"
@@ -11,52 +11,302 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
 	"time"
 )
 
+// paramLocation says where in a request a tested parameter lives.
+type paramLocation string
+
+const (
+	locQuery   paramLocation = "query"
+	locBody    paramLocation = "body"
+	locJSON    paramLocation = "json"     // string value in a JSON body, by JSON Pointer
+	locJSONKey paramLocation = "json-key" // object key in a JSON body, by JSON Pointer
+	locXML     paramLocation = "xml"      // element text or attribute in an XML body, by XPath
+
+	locMultipart     paramLocation = "multipart"          // text field of a multipart/form-data body
+	locMultipartFile paramLocation = "multipart-filename" // filename of a file field in a multipart body
+	locHeader        paramLocation = "header"             // request header, by name
+	locPath          paramLocation = "path"               // URL path segment, by position from 1
+	locDOM           paramLocation = "dom"                // query parameter or location.hash reaching a DOM sink
+)
+
 type paramCheck struct {
-	url   string
-	param string
+	url         string
+	param       string
+	loc         paramLocation
+	tmpl        *requestTemplate
+	headerValue string // sent in the param header by header checks
+	chunked     bool   // send the body with chunked transfer encoding
+}
+
+// stringList is a flag.Value that may be given more than once and also
+// accepts comma-separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
 }
 
 type Result struct {
-	URL          string   `json:"url"`
-	Param        string   `json:"param"`
-	Unfiltered   []string `json:"unfiltered"`
-	SQLInjection bool     `json:"sql_injection"`
+	URL               string        `json:"url" xml:"url"`
+	Param             string        `json:"param" xml:"param"`
+	Location          paramLocation `json:"location" xml:"location"`
+	Method            string        `json:"method,omitempty" xml:"method,omitempty"`
+	Body              string        `json:"body,omitempty" xml:"body,omitempty"`
+	Sink              string        `json:"sink,omitempty" xml:"sink,omitempty"`
+	StoredAt          string        `json:"stored_at,omitempty" xml:"stored_at,omitempty"`
+	Unfiltered        []string      `json:"unfiltered" xml:"unfiltered>char"`
+	SQLInjection      bool          `json:"sql_injection" xml:"sql_injection"`
+	CRLFInjection     bool          `json:"crlf_injection,omitempty" xml:"crlf_injection,omitempty"`
+	CommandInjection  bool          `json:"command_injection,omitempty" xml:"command_injection,omitempty"`
+	PathTraversal     bool          `json:"path_traversal,omitempty" xml:"path_traversal,omitempty"`
+	BlindSQLInjection string        `json:"blind_sql_injection,omitempty" xml:"blind_sql_injection,omitempty"`
+	Database          string        `json:"database,omitempty" xml:"database,omitempty"`
+	NoSQLInjection    string        `json:"nosql_injection,omitempty" xml:"nosql_injection,omitempty"`
+	SSRF              []string      `json:"ssrf,omitempty" xml:"ssrf>protocol,omitempty"`
+	SSTI              bool          `json:"ssti,omitempty" xml:"ssti,omitempty"`
+	XXE               string        `json:"xxe,omitempty" xml:"xxe,omitempty"`
+	XXEProtocols      []string      `json:"xxe_protocols,omitempty" xml:"xxe_protocols>protocol,omitempty"`
+	TemplateEngine    string        `json:"template_engine,omitempty" xml:"template_engine,omitempty"`
+	Severity          string        `json:"severity,omitempty" xml:"severity,omitempty"`
+	PoC               string        `json:"poc,omitempty" xml:"poc,omitempty"`
+	Curl              string        `json:"curl,omitempty" xml:"curl,omitempty"`
+	Evidence          string        `json:"evidence,omitempty" xml:"evidence,omitempty"`
+	Context           []string      `json:"context,omitempty" xml:"context>name,omitempty"`
+	Verified          bool          `json:"verified,omitempty" xml:"verified,omitempty"`
+	Payload           string        `json:"payload,omitempty" xml:"payload,omitempty"`
+	Payloads          []string      `json:"payloads,omitempty" xml:"payloads>payload,omitempty"`
+	Evasions          []evasionHit  `json:"evasions,omitempty" xml:"evasions>evasion,omitempty"`
+	Decoded           []evasionHit  `json:"decoded,omitempty" xml:"decoded>encoding,omitempty"`
+	Fingerprint       string        `json:"fingerprint,omitempty" xml:"fingerprint,omitempty"`
+	Capture           *exchange     `json:"capture,omitempty" xml:"capture,omitempty"`
+}
+
+// resultSink receives every finding in addition to the regular output.
+type resultSink interface {
+	write(Result) error
+	close() error
+}
+
+// errorSink is implemented by sinks that also keep track of requests that
+// failed during the scan.
+type errorSink interface {
+	writeError(url, param string, err error) error
+}
+
+var dialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: time.Second,
+	DualStack: true,
 }
 
 var transport = &http.Transport{
-	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	DialContext: (&net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: time.Second,
-		DualStack: true,
-	}).DialContext,
+	TLSClientConfig: &tls.Config{},
+	DialContext:     dialer.DialContext,
 }
 
 var httpClient = &http.Client{
 	Transport: transport,
-}
-
-var dbErrorPatterns = map[string][]string{
-	"PostgreSQL": {"PSQLException", "ERROR:", "unterminated quoted string", "syntax error at or near"},
-	"Oracle":     {"ORA-", "PLS-", "ORA-00933", "ORA-01756"},
-	"MSSQL":      {"SQLException", "Incorrect syntax near", "Unclosed quotation mark"},
-	"Generic":    {"SQL syntax"},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
 }
 
 func main() {
-	var inputFile string
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		runVerify(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "listen" {
+		runListen(os.Args[2:])
+		return
+	}
+
+	var inputFiles stringList
+	var targetURLs []string
+	var burpFile string
+	var sitemapTarget string
+	var rawRequestFile string
+	var harvestDomain string
+	var crawlerFile string
+	var jsonlFile string
+	var nmapFile string
+	var hostsFile string
+	var pathsFile string
+	var paramsFile string
+	var valuesFile string
+	var openAPIFile string
+	var openAPIBase string
+	var postmanFile string
+	var ffufFile string
+	var scopeFile string
+	var resumeFile string
+	var dbDSN string
+	var dbErrorsFile string
+	var dbQuery string
+	var dbTable string
 	var outputFile string
 	var numWorkers int
+	var probeMethod string
+	var useTestHeaders bool
+	var useDOM bool
+	var useCRLF bool
+	var useSSTI bool
+	var useCmdInjection bool
+	var useLFI bool
+	var useXXE bool
+	var useTimeSQLi bool
+	var useBooleanSQLi bool
+	var useNoSQL bool
+	var blindCallback string
+	var blindLog string
+	var ssrfCallback string
+	var ssrfListen string
+	var ssrfDNS string
+	var ssrfWait time.Duration
+	var storedViews string
+	var testHeaderNames stringList
 	var jsonOutput bool
-	flag.StringVar(&inputFile, "f", "", "file containing URLs to process")
+	var jsonlOutput bool
+	var outputFormat string
+	var reportFile string
+	var baselineFile string
+	var suppressFile string
+	var summary bool
+	var formatOpts formatOptions
+	var formatTemplate string
+	var outputDir string
+	var appendOutput bool
+	var capture bool
+	var rotateSizeMB int
+	var rotateEvery time.Duration
+	var liveDest string
+	var liveFormat string
+	var webhookURL string
+	var syslogDest string
+	var webhookHeaders []string
+	var notifyServices stringList
+	var notifyConfigFile string
+	var esURL string
+	var esIndex string
+	var scanID string
+	var scanHeader bool
+	var probeHosts bool
+	var useRobots bool
+	var follow bool
+	var shard shardSpec
+	var crawl bool
+	var crawlDepth int
+	var req requestFlags
+	req.register(flag.CommandLine)
+	flag.Var(&inputFiles, "f", "file containing URLs to process (repeatable or comma-separated)")
+	flag.Func("u", "URL to process, along with any other input (repeatable)", func(s string) error {
+		targetURLs = append(targetURLs, s)
+		return nil
+	})
+	flag.StringVar(&burpFile, "burp", "", "Burp Suite XML export to read URLs from")
+	flag.StringVar(&sitemapTarget, "sitemap", "", "host or base URL whose sitemap.xml to read URLs from")
+	flag.StringVar(&rawRequestFile, "r", "", "file containing a raw HTTP request to use as a template")
+	flag.StringVar(&jsonlFile, "input-jsonl", "", "JSONL file of targets with per-URL method, headers, cookies and body (- for stdin)")
+	flag.StringVar(&crawlerFile, "katana", "", "katana/hakrawler JSONL output to read URLs and forms from (- for stdin)")
+	flag.StringVar(&nmapFile, "nmap", "", "nmap/masscan XML report whose open web ports are used as base URLs")
+	flag.StringVar(&openAPIFile, "openapi", "", "OpenAPI/Swagger spec (YAML or JSON) to enumerate endpoints from")
+	flag.StringVar(&openAPIBase, "openapi-base", "", "base URL overriding or completing the server URL in the -openapi spec")
+	flag.StringVar(&ffufFile, "ffuf", "", "ffuf (or dirsearch) JSON output to read discovered endpoints from")
+	flag.StringVar(&postmanFile, "postman", "", "Postman v2 collection to read requests from")
+	flag.StringVar(&hostsFile, "hosts", "", "file of bare hosts to expand with the -paths and -param-wordlist wordlists")
+	flag.StringVar(&pathsFile, "paths", "", "wordlist of paths to try on each base URL")
+	flag.StringVar(&paramsFile, "param-wordlist", "", "wordlist of parameter names to try on each base URL (default: built-in list)")
+	flag.StringVar(&paramsFile, "params", "", "alias for -param-wordlist")
+	flag.StringVar(&valuesFile, "wordlist", "", "values to substitute for §NAME§ placeholders in input URLs")
+	flag.StringVar(&harvestDomain, "domain", "", "domain to harvest archived URLs for from the Wayback Machine and Common Crawl")
+	flag.StringVar(&dbErrorsFile, "db-errors", "", "YAML file of database error messages by engine to use instead of the built-in set")
+	flag.StringVar(&dbDSN, "db-dsn", "", "SQLite path or postgres:// DSN to write results to and, without -f, -u or piped input, read targets from")
+	flag.StringVar(&dbQuery, "db-query", "SELECT url FROM targets", "query returning target URLs in its first column")
+	flag.StringVar(&dbTable, "db-table", "kxss_results", "table to write results to")
 	flag.StringVar(&outputFile, "o", "", "file to write output to")
+	flag.BoolVar(&capture, "capture", false, "store the full request and base64 response of each finding in structured output")
+	flag.BoolVar(&appendOutput, "append", false, "append to the -o file (or -output-dir files) instead of truncating it")
+	flag.IntVar(&rotateSizeMB, "rotate-size", 0, "start a new -o file once it reaches this many megabytes")
+	flag.DurationVar(&rotateEvery, "rotate-every", 0, "start a new -o file at this interval, e.g. 1h")
+	flag.StringVar(&outputDir, "output-dir", "", "directory to write one output file per host to instead of -o")
+	flag.StringVar(&scopeFile, "scope", "", "YAML file with include/exclude scope rules")
+	flag.Func("shard", "only scan shard K of N of the input, e.g. 3/10", func(s string) error {
+		var err error
+		shard, err = parseShard(s)
+		return err
+	})
+	flag.StringVar(&baselineFile, "baseline", "", "JSON results of an earlier scan; only new findings are output and fixed ones are listed at the end")
+	flag.StringVar(&suppressFile, "suppress", "", "JSON list of finding fingerprints (or earlier results) to leave out of the output")
+	flag.StringVar(&resumeFile, "resume", "", "state file used to checkpoint progress and resume an interrupted scan")
+	flag.StringVar(&blindCallback, "blind", "", "callback URL, e.g. https://x.collab.example, for a blind XSS payload sent to every parameter")
+	flag.StringVar(&blindLog, "blind-log", "blind.jsonl", "file to append the ID, URL and parameter of each -blind and -ssrf injection to")
+	flag.StringVar(&ssrfCallback, "ssrf", "", "callback URL, e.g. http://x.collab.example, to set parameters with URL-shaped values to, reporting those the server fetches (SSRF)")
+	flag.StringVar(&ssrfListen, "ssrf-listen", "", "address to serve -ssrf HTTP callbacks on during the scan, e.g. 203.0.113.5:80")
+	flag.StringVar(&ssrfDNS, "ssrf-dns", "", "address to answer -ssrf DNS lookups on during the scan, e.g. :53, pointing them at the -ssrf-listen host")
+	flag.DurationVar(&ssrfWait, "ssrf-wait", 10*time.Second, "how long to wait for late -ssrf callbacks after the scan")
+	flag.StringVar(&storedViews, "stored-views", "", "file of pages to search, after the scan, for unique canaries sent to every parameter (stored XSS)")
+	flag.BoolVar(&confirmFindings, "verify", false, "confirm findings by sending a harmless payload suited to the reflection context and checking it comes back as markup")
+	flag.BoolVar(&probeEncodings, "encoded-probes", false, "retry filtered characters pre-encoded (double and triple URL, HTML entity, \\u and %u escapes) to find input decoded again before output")
+	flag.BoolVar(&useCRLF, "crlf", false, "also append CRLF sequences with a marker header to every parameter and report those that add it to the response (CRLF injection)")
+	flag.BoolVar(&useCmdInjection, "cmdi", false, "also append shell commands like ;sleep 7 to every parameter and report those that consistently delay the response (command injection)")
+	flag.BoolVar(&useLFI, "lfi", false, "also set parameters that look like file names to traversal paths like ../../etc/passwd and report those that return the file (path traversal)")
+	flag.BoolVar(&useXXE, "xxe", false, "also declare external entities in XML bodies and report those the parser resolves, by file contents, error messages or, with -ssrf, callbacks (XXE)")
+	flag.BoolVar(&useBooleanSQLi, "sqli-bool", false, "also append true and false conditions like ' AND '1'='1 to every parameter and report those where only the false one changes the page (blind SQL injection)")
+	flag.BoolVar(&useTimeSQLi, "sqli-time", false, "also append SLEEP, pg_sleep and WAITFOR DELAY probes to every parameter and report those that consistently delay the response (blind SQL injection)")
+	flag.BoolVar(&useNoSQL, "nosql", false, "also send MongoDB operators like param[$ne]= and conditions like '||'1'=='1 to every parameter and report those that draw database errors or change the page (NoSQL injection)")
+	flag.BoolVar(&useSSTI, "ssti", false, "also append template expressions like {{1337*7331}} to every parameter and report those evaluated, naming the likely engine (template injection)")
+	flag.BoolVar(&useDOM, "dom", false, "also load URLs in headless Chrome with the canary in the query and fragment and report DOM sinks it reaches")
+	flag.BoolVar(&useTestHeaders, "test-headers", false, "also inject the canary into request headers and report those reflected as header findings")
+	flag.BoolVar(&testPath, "test-path", false, "also test URL path segments, e.g. /search/TERM/results, for reflection")
+	flag.Var(&testHeaderNames, "test-header-names", "headers for -test-headers (default: Referer, User-Agent, X-Forwarded-For, X-Forwarded-Host)")
+	flag.BoolVar(&jsonKeys, "json-keys", false, "also test the object keys of JSON request bodies, not just their string values")
+	flag.StringVar(&probeMethod, "method", "GET", "how to send the query parameters of input URLs: GET, POST (as a form body) or BOTH")
 	flag.IntVar(&numWorkers, "w", 40, "number of worker goroutines")
 	flag.BoolVar(&jsonOutput, "j", false, "output results in JSON format")
+	flag.BoolVar(&jsonlOutput, "jsonl", false, "output results as JSON Lines, one compact object per finding")
+	flag.BoolVar(&summary, "summary", false, "finish with an aggregate of findings per host, character, kind of injection and parameter")
+	flag.StringVar(&reportFile, "report", "", "file to write a self-contained HTML report to")
+	flag.StringVar(&syslogDest, "syslog", "", "send findings as RFC 5424 syslog messages to udp://, tcp:// or tls://host:port")
+	flag.StringVar(&webhookURL, "webhook", "", "URL to POST each finding to as JSON as soon as it is found")
+	flag.Func("webhook-header", "header to send with -webhook requests, e.g. \"Authorization: Bearer TOKEN\" (repeatable)", func(s string) error {
+		webhookHeaders = append(webhookHeaders, s)
+		return nil
+	})
+	flag.Var(&notifyServices, "notify", "chat service to alert on each finding: slack, discord or telegram (repeatable)")
+	flag.StringVar(&notifyConfigFile, "notify-config", "", "YAML file with the -notify service credentials")
+	flag.StringVar(&esURL, "es-url", "", "Elasticsearch/OpenSearch base URL to bulk-index findings into (user:pass@ allowed)")
+	flag.StringVar(&esIndex, "es-index", "kxss", "index to write -es-url findings to")
+	flag.StringVar(&scanID, "scan-id", "", "identifier stored with indexed and -format sqlite findings (default: random)")
+	flag.BoolVar(&scanHeader, "scan-header", false, "send the -scan-id as X-Kxss-Scan and a per-request X-Kxss-Request ID with every request")
+	flag.StringVar(&outputFormat, "format", "text", "output format: text, json, jsonl, tsv, sarif, markdown, xml, burp or sqlite")
+	flag.StringVar(&liveDest, "live", "", "also stream findings as they are found to stderr, tcp://host:port or unix:///path")
+	flag.StringVar(&liveFormat, "live-format", "text", "format of the -live stream: text, jsonl or tsv")
+	flag.StringVar(&formatTemplate, "format-template", "", "Go text/template for each finding, e.g. '{{.URL}} {{.Param}} {{join .Unfiltered \",\"}}' (implies -format template)")
+	flag.StringVar(&formatOpts.delim, "delim", "\t", "field separator for -format tsv")
+	flag.BoolVar(&formatOpts.curl, "curl", false, "print the curl command reproducing each finding in text output")
+	flag.BoolVar(&follow, "follow", false, "keep reading the input file (or stdin) for new URLs instead of stopping at EOF")
+	flag.BoolVar(&crawl, "crawl", false, "spider same-origin links and forms from each input URL and scan what is found")
+	flag.IntVar(&crawlDepth, "depth", 2, "maximum link depth for -crawl")
+	flag.BoolVar(&useRobots, "robots", false, "also scan parameterized Allow/Disallow paths from each host's robots.txt")
+	flag.BoolVar(&probeHosts, "probe", false, "probe each origin once, skip URLs on hosts that do not respond and list them at the end")
 	flag.Parse()
 
 	if numWorkers < 1 {
@@ -64,26 +314,224 @@ func main() {
 		os.Exit(1)
 	}
 
-	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
+	if formatTemplate != "" {
+		tmpl, err := parseFormatTemplate(formatTemplate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error parsing format template: %s\n", err)
+			os.Exit(1)
+		}
+		formatOpts.template = tmpl
+		outputFormat = "template"
 	}
 
-	var scanner *bufio.Scanner
-	if inputFile != "" {
-		file, err := os.Open(inputFile)
+	// -j and -jsonl are shorthands for the matching -format
+	switch {
+	case jsonlOutput:
+		outputFormat = "jsonl"
+	case jsonOutput:
+		outputFormat = "json"
+	}
+
+	captureExchanges = capture
+	if dbErrorsFile != "" {
+		sigs, err := loadDBErrors(dbErrorsFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error opening input file %s: %s\n", inputFile, err)
+			fmt.Fprintf(os.Stderr, "error loading database errors %s: %s\n", dbErrorsFile, err)
+			os.Exit(1)
+		}
+		dbErrorPatterns = sigs
+	}
+	if useTestHeaders {
+		testHeaders = defaultTestHeaders
+		if len(testHeaderNames) > 0 {
+			testHeaders = testHeaderNames
+		}
+	}
+
+	withMethods, err := methodExpander(probeMethod)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error in -method: %s\n", err)
+		os.Exit(1)
+	}
+
+	if useCRLF {
+		injectionChecks = append(injectionChecks, crlfCheck)
+	}
+	if useSSTI {
+		injectionChecks = append(injectionChecks, sstiCheck)
+	}
+	if useCmdInjection {
+		if req.timeout != 0 && req.timeout <= injectionDelay {
+			fmt.Fprintf(os.Stderr, "-cmdi needs a -timeout longer than %s\n", injectionDelay)
+			os.Exit(1)
+		}
+		injectionChecks = append(injectionChecks, cmdCheck)
+	}
+	if useLFI {
+		injectionChecks = append(injectionChecks, traversalCheck)
+	}
+	if useXXE {
+		injectionChecks = append(injectionChecks, xxeCheck)
+	}
+	if useTimeSQLi {
+		if req.timeout != 0 && req.timeout <= injectionDelay {
+			fmt.Fprintf(os.Stderr, "-sqli-time needs a -timeout longer than %s\n", injectionDelay)
+			os.Exit(1)
+		}
+		injectionChecks = append(injectionChecks, sqliTimeCheck)
+	}
+	if useBooleanSQLi {
+		injectionChecks = append(injectionChecks, sqliBooleanCheck)
+	}
+	if useNoSQL {
+		injectionChecks = append(injectionChecks, nosqlCheck)
+	}
+	var dom domScanner
+	if useDOM {
+		if newDOMScanner == nil {
+			fmt.Fprintf(os.Stderr, "-dom is not available in this build (built with -tags nochromedp)\n")
+			os.Exit(1)
+		}
+		dom, err = newDOMScanner(domOptions{proxy: req.proxy, insecure: req.insecure, timeout: req.timeout})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error starting headless Chrome for -dom: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if scanID == "" {
+		scanID = newScanID()
+	}
+	var blind *blindInjector
+	if blindCallback != "" {
+		blind, err = newBlindInjector(blindCallback, blindLog, scanID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error in -blind: %s\n", err)
+			os.Exit(1)
+		}
+		defer blind.close()
+	}
+	var ssrf *ssrfDetector
+	if ssrfCallback != "" {
+		ssrf, err = newSSRFDetector(ssrfCallback, blindLog, scanID, ssrfWait)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error in -ssrf: %s\n", err)
+			os.Exit(1)
+		}
+		defer ssrf.close()
+		ssrf.xxe = useXXE
+		if err := ssrf.listen(ssrfListen, ssrfDNS, blindLog); err != nil {
+			fmt.Fprintf(os.Stderr, "error starting -ssrf listener: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	var stored *storedXSS
+	if storedViews != "" {
+		stored, err = newStoredXSS(storedViews)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading view pages %s: %s\n", storedViews, err)
+			os.Exit(1)
+		}
+	}
+	if scanHeader {
+		headerScanID = scanID
+	}
+	req.apply()
+
+	if scopeFile != "" {
+		s, err := loadScope(scopeFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading scope file %s: %s\n", scopeFile, err)
+			os.Exit(1)
+		}
+		scope = s
+	}
+
+	var base *baselineSet
+	if baselineFile != "" {
+		var err error
+		if base, err = loadBaseline(baselineFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading baseline %s: %s\n", baselineFile, err)
+			os.Exit(1)
+		}
+	}
+
+	var suppress suppressSet
+	if suppressFile != "" {
+		var err error
+		if suppress, err = loadSuppress(suppressFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading suppress file %s: %s\n", suppressFile, err)
+			os.Exit(1)
+		}
+	}
+
+	var cp *checkpoint
+	resuming := false
+	if resumeFile != "" {
+		var err error
+		cp, resuming, err = loadCheckpoint(resumeFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading resume state %s: %s\n", resumeFile, err)
+			os.Exit(1)
+		}
+	}
+
+	var inputs []inputSource
+	for i, name := range inputFiles {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening input file %s: %s\n", name, err)
 			os.Exit(1)
 		}
 		defer file.Close()
-		scanner = bufio.NewScanner(file)
-	} else {
-		scanner = bufio.NewScanner(os.Stdin)
+		var in io.Reader = file
+		if follow && i == len(inputFiles)-1 {
+			// Only the last file can be followed, the others have to end
+			in = followReader{file}
+		}
+		r, closeFn, err := decompress(in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error decompressing input file %s: %s\n", name, err)
+			os.Exit(1)
+		}
+		defer closeFn()
+		inputs = append(inputs, inputSource{name, r})
+	}
+
+	var paths []string
+	if pathsFile != "" {
+		var err error
+		if paths, err = readWordlist(pathsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading paths wordlist %s: %s\n", pathsFile, err)
+			os.Exit(1)
+		}
+	}
+	params := defaultParams
+	if paramsFile != "" {
+		var err error
+		if params, err = readWordlist(paramsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading params wordlist %s: %s\n", paramsFile, err)
+			os.Exit(1)
+		}
+	}
+
+	var values []string
+	if valuesFile != "" {
+		var err error
+		if values, err = readWordlist(valuesFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading values wordlist %s: %s\n", valuesFile, err)
+			os.Exit(1)
+		}
 	}
 
-	var out *os.File
-	if outputFile != "" {
-		file, err := os.Create(outputFile)
+	var out io.Writer
+	if outputFile != "" && outputDir != "" {
+		fmt.Fprintf(os.Stderr, "-o and -output-dir cannot be used together\n")
+		os.Exit(1)
+	}
+	if outputFile != "" && outputFormat != "sqlite" {
+		// When resuming, keep the findings written before the interruption
+		file, err := openRotatingFile(outputFile, appendOutput || resuming, int64(rotateSizeMB)<<20, rotateEvery)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error creating output file %s: %s\n", outputFile, err)
 			os.Exit(1)
@@ -94,95 +542,541 @@ func main() {
 		out = os.Stdout
 	}
 
+	var sinks []resultSink
+	switch {
+	case outputDir != "":
+		if _, ok := formatExtensions[outputFormat]; !ok {
+			fmt.Fprintf(os.Stderr, "-output-dir does not support -format %s\n", outputFormat)
+			os.Exit(1)
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "error creating output directory %s: %s\n", outputDir, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, newHostSplitSink(outputDir, outputFormat, formatOpts, appendOutput || resuming))
+	case outputFormat == "sqlite":
+		if outputFile == "" {
+			fmt.Fprintf(os.Stderr, "-format sqlite needs a database file given with -o\n")
+			os.Exit(1)
+		}
+		sink, err := newScanDBSink(outputFile, scanID, os.Args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening results database %s: %s\n", outputFile, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	default:
+		sink, err := newFormatSink(outputFormat, out, formatOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if liveDest != "" {
+		sink, err := newLiveSink(liveDest, liveFormat, formatOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening live stream %s: %s\n", liveDest, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if reportFile != "" {
+		sinks = append(sinks, &htmlReportSink{path: reportFile})
+	}
+
+	if webhookURL != "" {
+		sink, err := newWebhookSink(webhookURL, webhookHeaders)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error configuring webhook: %s\n", err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(notifyServices) > 0 {
+		if notifyConfigFile == "" {
+			fmt.Fprintf(os.Stderr, "-notify needs a config file given with -notify-config\n")
+			os.Exit(1)
+		}
+		cfg, err := loadNotifyConfig(notifyConfigFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading notify config %s: %s\n", notifyConfigFile, err)
+			os.Exit(1)
+		}
+		for _, service := range notifyServices {
+			sink, err := newNotifySink(service, cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error configuring notifications: %s\n", err)
+				os.Exit(1)
+			}
+			sinks = append(sinks, sink)
+		}
+	}
+
+	if syslogDest != "" {
+		sink, err := newSyslogSink(syslogDest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error connecting to syslog %s: %s\n", syslogDest, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if esURL != "" {
+		sinks = append(sinks, newESSink(esURL, esIndex, scanID))
+	}
+
+	var db *sql.DB
+	if dbDSN != "" {
+		var driver string
+		var err error
+		db, driver, err = openDB(dbDSN)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening database: %s\n", err)
+			os.Exit(1)
+		}
+		sink, err := newDBSink(db, driver, dbTable)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error preparing results table %s: %s\n", dbTable, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	var resultsMu sync.Mutex
 	results := []Result{}
 	initialChecks := make(chan paramCheck, numWorkers)
 
-	appendChecks := makePool(initialChecks, numWorkers, func(c paramCheck, output chan paramCheck) {
-		reflected, err := checkReflected(c.url)
-		if err != nil {
+	recordError := func(c paramCheck, scanErr error) {
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
+		for _, sink := range sinks {
+			if es, ok := sink.(errorSink); ok {
+				if err := es.writeError(c.url, c.param, scanErr); err != nil {
+					fmt.Fprintf(os.Stderr, "error recording failure for %s: %s\n", c.url, err)
+				}
+			}
+		}
+	}
+
+	// admit is the filter every input goes through, including what the
+	// scan discovers along the way
+	admit := func(c paramCheck) bool {
+		return shard.includes(c.url) && scope.allows(c.url) && !cp.skip(c)
+	}
+
+	liveChecks := initialChecks
+	var liveness *livenessFilter
+	if probeHosts {
+		liveness = newLivenessFilter()
+		liveChecks = makePool(initialChecks, numWorkers, liveness.filter)
+	}
+
+	if crawl {
+		liveChecks = makePool(liveChecks, numWorkers, newSiteCrawler(crawlDepth).expand)
+	}
+	if useRobots {
+		liveChecks = makePool(liveChecks, numWorkers, newRobotsExpander(admit).expand)
+	}
+	if withMethods != nil {
+		liveChecks = makePool(liveChecks, numWorkers, withMethods)
+	}
+	if blind != nil {
+		liveChecks = makePool(liveChecks, numWorkers, blind.inject)
+	}
+	if ssrf != nil {
+		liveChecks = makePool(liveChecks, numWorkers, ssrf.inject)
+	}
+	if stored != nil {
+		liveChecks = makePool(liveChecks, numWorkers, stored.inject)
+	}
+
+	report := func(result Result) {
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
+		if suppress.suppressed(result) || !base.isNew(result) {
 			return
 		}
-		if len(reflected) == 0 {
+		// Real-time output
+		for _, sink := range sinks {
+			if err := sink.write(result); err != nil {
+				fmt.Fprintf(os.Stderr, "error writing result for %s: %s\n", result.URL, err)
+			}
+		}
+		results = append(results, result)
+	}
+	if len(injectionChecks) > 0 {
+		liveChecks = makePool(liveChecks, numWorkers, injectionStage(report))
+	}
+	if dom != nil {
+		defer dom.close()
+		liveChecks = makePool(liveChecks, domTabs, domStage(dom, report))
+	}
+
+	appendChecks := makePool(liveChecks, numWorkers, func(c paramCheck, output chan paramCheck) {
+		reflected, err := checkReflected(c)
+		if err == nil && testHeaders != nil {
+			var headers []paramCheck
+			headers, err = checkHeaderReflection(c)
+			reflected = append(reflected, headers...)
+		}
+		if err != nil {
+			recordError(c, err)
 			return
 		}
-		for _, param := range reflected {
-			output <- paramCheck{c.url, param}
+		for _, rc := range cp.start(c, reflected) {
+			output <- rc
 		}
 	})
 
 	charChecks := makePool(appendChecks, numWorkers, func(c paramCheck, output chan paramCheck) {
-		wasReflected, isError, err := checkAppend(c.url, c.param, "iy3j4h234hjb23234")
+		wasReflected, isError, err := checkAppend(c, reflectionCanary)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error from checkAppend for url %s with param %s: %s\n", c.url, c.param, err)
+			recordError(c, err)
 			return
 		}
 		if wasReflected || isError {
-			output <- paramCheck{c.url, c.param}
+			output <- c
+		} else {
+			cp.finish(c)
 		}
 	})
 
 	done := makePool(charChecks, numWorkers, func(c paramCheck, output chan paramCheck) {
-		output_of_url := []string{c.url, c.param}
-		sqlInjection := false
-		for _, char := range []string{"\"", "'", "<", ">", "$", "|", "(", ")", "`", ":", ";", "{", "}"} {
-			wasReflected, isError, err := checkAppend(c.url, c.param, char)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "error from checkAppend for url %s with param %s with %s: %s\n", c.url, c.param, char, err)
+		defer cp.finish(c)
+		result := checkChars(c)
+		if result.isFinding() {
+			report(result)
+		}
+	})
+
+	feed := func(c paramCheck) {
+		if admit(c) {
+			initialChecks <- c
+		}
+	}
+
+	stopCheckpoint := make(chan struct{})
+	onCheckpointErr := func(err error) {
+		fmt.Fprintf(os.Stderr, "error saving resume state %s: %s\n", resumeFile, err)
+	}
+	go cp.saveEvery(checkpointInterval, stopCheckpoint, onCheckpointErr)
+	if cp != nil {
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		go func() {
+			<-interrupted
+			if err := cp.save(); err != nil {
+				onCheckpointErr(err)
+			}
+			os.Exit(130)
+		}()
+	}
+
+	// Merge all inputs, skipping URLs already seen in an earlier line or file
+	seen := make(map[string]bool)
+	feedLine := func(line string) {
+		for _, u := range expandTemplate(line, values) {
+			if seen[u] {
 				continue
 			}
-			if wasReflected {
-				output_of_url = append(output_of_url, char)
+			seen[u] = true
+			feed(paramCheck{url: u})
+		}
+	}
+	// -u URLs are scanned along with whatever other input is given
+	for _, u := range targetURLs {
+		feedLine(u)
+	}
+
+	switch {
+	case burpFile != "":
+		urls, err := readBurpURLs(burpFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading Burp export %s: %s\n", burpFile, err)
+			os.Exit(1)
+		}
+		for _, u := range urls {
+			feed(paramCheck{url: u})
+		}
+	case jsonlFile != "":
+		r, closeFn, err := openInput(jsonlFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening JSONL input %s: %s\n", jsonlFile, err)
+			os.Exit(1)
+		}
+		defer closeFn()
+		if err := readJSONLChecks(r, feed); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading JSONL input %s: %s\n", jsonlFile, err)
+			os.Exit(1)
+		}
+	case crawlerFile != "":
+		r, closeFn, err := openInput(crawlerFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening crawler output %s: %s\n", crawlerFile, err)
+			os.Exit(1)
+		}
+		defer closeFn()
+		if err := readCrawlerChecks(r, feed); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading crawler output %s: %s\n", crawlerFile, err)
+			os.Exit(1)
+		}
+	case nmapFile != "":
+		bases, err := readNmapBaseURLs(nmapFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading scan report %s: %s\n", nmapFile, err)
+			os.Exit(1)
+		}
+		for _, base := range bases {
+			for _, u := range expandBaseURL(base, paths, params) {
+				feed(paramCheck{url: u})
 			}
-			if isError {
-				sqlInjection = true
+		}
+	case openAPIFile != "":
+		checks, err := readOpenAPIChecks(openAPIFile, openAPIBase)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading OpenAPI spec %s: %s\n", openAPIFile, err)
+			os.Exit(1)
+		}
+		for _, c := range checks {
+			feed(c)
+		}
+	case postmanFile != "":
+		checks, err := readPostmanChecks(postmanFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading Postman collection %s: %s\n", postmanFile, err)
+			os.Exit(1)
+		}
+		for _, c := range checks {
+			feed(c)
+		}
+	case ffufFile != "":
+		checks, err := readFfufChecks(ffufFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading ffuf output %s: %s\n", ffufFile, err)
+			os.Exit(1)
+		}
+		for _, c := range checks {
+			feed(c)
+		}
+	case hostsFile != "":
+		hosts, err := readWordlist(hostsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading hosts file %s: %s\n", hostsFile, err)
+			os.Exit(1)
+		}
+		for _, host := range hosts {
+			for _, u := range expandBaseURL(hostBaseURL(host, req.scheme), paths, params) {
+				feed(paramCheck{url: u})
 			}
 		}
-		if len(output_of_url) > 2 || sqlInjection {
-			result := Result{
-				URL:          output_of_url[0],
-				Param:        output_of_url[1],
-				Unfiltered:   output_of_url[2:],
-				SQLInjection: sqlInjection,
+	case harvestDomain != "":
+		harvestURLs(harvestDomain, func(u string) {
+			feed(paramCheck{url: u})
+		}, func(source string, err error) {
+			fmt.Fprintf(os.Stderr, "error harvesting URLs from %s for %s: %s\n", source, harvestDomain, err)
+		})
+	case rawRequestFile != "":
+		u, tmpl, err := readRawRequest(rawRequestFile, req.scheme)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading raw request %s: %s\n", rawRequestFile, err)
+			os.Exit(1)
+		}
+		// The request's own header order comes before -H additions
+		headerOrder = append(tmpl.Order, headerOrder...)
+		feed(paramCheck{url: u, tmpl: tmpl})
+	case sitemapTarget != "":
+		urls, err := readSitemapURLs(sitemapTarget)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading sitemap for %s: %s\n", sitemapTarget, err)
+			os.Exit(1)
+		}
+		for _, u := range urls {
+			feed(paramCheck{url: u})
+		}
+	case db != nil && len(inputs) == 0 && len(targetURLs) == 0 && !stdinPiped():
+		// Without other input the database holds the targets too
+		urls, err := readDBURLs(db, dbQuery)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading targets from database: %s\n", err)
+			os.Exit(1)
+		}
+		for _, u := range urls {
+			feed(paramCheck{url: u})
+		}
+	default:
+		if len(inputs) == 0 && len(targetURLs) == 0 {
+			var in io.Reader = os.Stdin
+			if follow {
+				in = followReader{os.Stdin}
 			}
-			// Real-time output
-			if jsonOutput {
-				jsonData, err := json.MarshalIndent(result, "", "  ")
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "error marshaling JSON for %s: %s\n", c.url, err)
-				} else {
-					fmt.Fprintln(out, string(jsonData))
-				}
-			} else {
-				if result.SQLInjection {
-					fmt.Fprintf(out, "URL: %s Param: %s [Possible SQL Injection] Unfiltered: %v\n", result.URL, result.Param, result.Unfiltered)
-				} else {
-					fmt.Fprintf(out, "URL: %s Param: %s Unfiltered: %v\n", result.URL, result.Param, result.Unfiltered)
-				}
+			r, closeFn, err := decompress(in)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error decompressing input: %s\n", err)
+				os.Exit(1)
+			}
+			defer closeFn()
+			inputs = append(inputs, inputSource{"stdin", r})
+		}
+		for _, in := range inputs {
+			scanner := bufio.NewScanner(in.r)
+			for scanner.Scan() {
+				feedLine(scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				fmt.Fprintf(os.Stderr, "error reading input %s: %s\n", in.name, err)
+				os.Exit(1)
 			}
-			results = append(results, result)
 		}
-	})
-
-	for scanner.Scan() {
-		initialChecks <- paramCheck{url: scanner.Text()}
-	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "error reading input: %s\n", err)
-		os.Exit(1)
 	}
 
 	close(initialChecks)
 	<-done
+	if stored != nil {
+		stored.check(report)
+	}
+	if ssrf != nil {
+		ssrf.check(report)
+	}
+
+	for _, sink := range sinks {
+		if err := sink.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing output: %s\n", err)
+		}
+	}
+
+	base.report(os.Stderr)
+
+	if liveness != nil {
+		alive, dead := liveness.counts()
+		fmt.Fprintf(os.Stderr, "probed %d origins: %d alive, %d dead\n", alive+dead, alive, dead)
+		for _, d := range liveness.dead() {
+			fmt.Fprintf(os.Stderr, "dead origin %s: %s\n", d.origin, d.err)
+		}
+	}
 
-	// Optional: Print a message if no vulnerabilities were found
+	close(stopCheckpoint)
+	if err := cp.save(); err != nil {
+		onCheckpointErr(err)
+	}
+
+	// Optional: Print a message if no vulnerabilities were found, on stderr
+	// for formats other tools read back
 	if len(results) == 0 {
-		fmt.Fprintln(out, "No vulnerabilities found.")
+		var w io.Writer = os.Stderr
+		if outputFormat == "text" {
+			w = out
+		}
+		fmt.Fprintln(w, "No vulnerabilities found.")
+	}
+
+	if summary && len(results) > 0 {
+		// Only the text format has room for prose; keep the others parseable
+		var w io.Writer = os.Stderr
+		if outputFormat == "text" {
+			w = out
+		}
+		if err := writeSummary(w, results); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing summary: %s\n", err)
+		}
 	}
 }
 
-func checkReflected(targetURL string) ([]string, error) {
-	out := make([]string, 0)
-	resp, err := doRequestWithRetries("GET", targetURL, nil, 3)
+// reflectionCanary is appended to a parameter value to confirm that the
+// parameter, and not just its original value, is reflected.
+const reflectionCanary = "iy3j4h234hjb23234"
+
+// specialChars are appended one at a time to a reflected parameter to
+// find out which of them survive unfiltered.
+var specialChars = []string{"\"", "'", "<", ">", "$", "|", "(", ")", "`", ":", ";", "{", "}"}
+
+// checkChars tests every special character against c and returns the
+// outcome as a Result, which is a finding only if something was
+// unfiltered or a database error showed up.
+func checkChars(c paramCheck) Result {
+	result := Result{
+		URL:        c.url,
+		Param:      c.param,
+		Location:   c.loc,
+		Unfiltered: []string{},
+	}
+	// Keep what it takes to send the request again, e.g. for kxss verify
+	if c.tmpl != nil {
+		result.Method = c.tmpl.Method
+		result.Body = c.tmpl.Body
+	}
+	for _, char := range specialChars {
+		marker := reflectionCanary + char
+		testURL, testBody, err := c.withSuffix(marker)
+		var p appendProbe
+		if err == nil {
+			p, err = probeAppend(c.withHeaderSuffix(marker), testURL, testBody, marker)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error from checkAppend for url %s with param %s with %s: %s\n", c.url, c.param, char, err)
+			continue
+		}
+		// A WAF blocking the probe hides whether the application filters
+		// the character, so try again in disguise
+		if p.blocked && !p.reflected {
+			technique, err := evadeBlock(c, char)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error evading block for url %s with param %s with %s: %s\n", c.url, c.param, char, err)
+			}
+			if technique != "" {
+				p.reflected = true
+				result.Evasions = append(result.Evasions, evasionHit{Char: char, Technique: technique})
+			}
+		}
+		// Applications that decode input again before output let through
+		// characters their filter only knows in plain form
+		if !p.reflected && probeEncodings {
+			encoding, err := probeEncoded(c, char)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error from encoded probe for url %s with param %s with %s: %s\n", c.url, c.param, char, err)
+			}
+			if encoding != "" {
+				p.reflected = true
+				result.Decoded = append(result.Decoded, evasionHit{Char: char, Technique: encoding})
+			}
+		}
+		if p.reflected {
+			result.Unfiltered = append(result.Unfiltered, char)
+		}
+		if p.isError {
+			result.SQLInjection = true
+			result.Database = p.database
+		}
+	}
+	result.Severity = classifySeverity(result)
+	if result.Severity != "" {
+		result.PoC = pocURL(c)
+		result.Curl = curlCommand(c)
+		result.Evidence, result.Context, result.Capture = reflectionEvidence(c)
+		if confirmFindings {
+			if payload, evidence, ok := confirmXSS(c, result); ok {
+				result.Verified = true
+				result.Payload = payload
+				result.Evidence = evidence
+				result.Severity = classifySeverity(result)
+			}
+		}
+		result.Fingerprint = fingerprint(result)
+	}
+	return result
+}
+
+func checkReflected(c paramCheck) ([]paramCheck, error) {
+	out := make([]paramCheck, 0)
+	body := ""
+	if c.tmpl != nil {
+		body = c.tmpl.Body
+	}
+	resp, err := c.send(c.url, body)
 	if err != nil {
 		return out, err
 	}
@@ -199,105 +1093,323 @@ func checkReflected(targetURL string) ([]string, error) {
 		return out, nil
 	}
 	ct := resp.Header.Get("Content-Type")
-	if ct != "" && !strings.Contains(ct, "html") {
+	respBody := string(b)
+
+	// A JSON or XML response can still reflect a body of the same kind
+	for _, p := range structuredParams(c.tmpl) {
+		if reflectableType(ct, p.loc) && strings.Contains(respBody, p.value) {
+			out = append(out, paramCheck{url: c.url, param: p.name, loc: p.loc, tmpl: c.tmpl})
+		}
+	}
+	if !reflectableType(ct, locQuery) {
 		return out, nil
 	}
 
-	body := string(b)
-	u, err := url.Parse(targetURL)
+	u, err := url.Parse(c.url)
 	if err != nil {
 		return out, err
 	}
 
 	for key, vv := range u.Query() {
 		for _, v := range vv {
-			if !strings.Contains(body, v) {
+			if !strings.Contains(respBody, v) {
+				continue
+			}
+			out = append(out, paramCheck{url: c.url, param: key, loc: locQuery, tmpl: c.tmpl})
+		}
+	}
+	if testPath {
+		out = append(out, pathChecks(c, respBody)...)
+	}
+
+	if c.tmpl.hasFormBody() {
+		form, err := url.ParseQuery(c.tmpl.Body)
+		if err != nil {
+			return out, err
+		}
+		for key, vv := range form {
+			if csrf.isToken(key) {
 				continue
 			}
-			out = append(out, key)
+			for _, v := range vv {
+				if !strings.Contains(respBody, v) {
+					continue
+				}
+				out = append(out, paramCheck{url: c.url, param: key, loc: locBody, tmpl: c.tmpl})
+			}
 		}
 	}
 	return out, nil
 }
 
-func checkAppend(targetURL, param, suffix string) (bool, bool, error) {
-	u, err := url.Parse(targetURL)
+// withSuffix returns the URL and body to send for c with suffix appended
+// to the value of its parameter.
+func (c paramCheck) withSuffix(suffix string) (string, string, error) {
+	body := ""
+	if c.tmpl != nil {
+		body = c.tmpl.Body
+	}
+
+	if c.loc == locHeader {
+		// The suffix goes in the header, see withHeaderSuffix
+		return c.url, body, nil
+	}
+	if c.loc == locPath {
+		u, err := editPathSegment(c.url, c.param, func(s string) string {
+			return s + url.PathEscape(suffix)
+		})
+		return u, body, err
+	}
+
+	if c.loc == locBody {
+		form, err := url.ParseQuery(body)
+		if err != nil {
+			return "", "", err
+		}
+		form.Set(c.param, form.Get(c.param)+suffix)
+		return c.url, form.Encode(), nil
+	}
+	if out, ok, err := editStructuredBody(c, body, func(v string) string {
+		return v + suffix
+	}); ok {
+		return c.url, out, err
+	}
+
+	u, err := url.Parse(c.url)
 	if err != nil {
-		return false, false, err
+		return "", "", err
+	}
+	if rawPaths {
+		if q, ok := editRawQuery(u.RawQuery, c.param, func(v string) string {
+			return v + url.QueryEscape(suffix)
+		}); ok {
+			return replaceRawQuery(c.url, q), body, nil
+		}
 	}
 	qs := u.Query()
-	val := qs.Get(param)
-	qs.Set(param, val+suffix)
+	val := qs.Get(c.param)
+	qs.Set(c.param, val+suffix)
 	u.RawQuery = qs.Encode()
+	return u.String(), body, nil
+}
 
-	// Perform base request for comparison
-	baseResp, err := doRequestWithRetries("GET", targetURL, nil, 3)
+// withValue returns the URL and body of c's request with the tested
+// parameter set to value. Header checks leave both as they are.
+func (c paramCheck) withValue(value string) (string, string, error) {
+	body := ""
+	if c.tmpl != nil {
+		body = c.tmpl.Body
+	}
+
+	if c.loc == locHeader {
+		return c.url, body, nil
+	}
+	if c.loc == locPath {
+		u, err := editPathSegment(c.url, c.param, func(string) string {
+			return url.PathEscape(value)
+		})
+		return u, body, err
+	}
+	if c.loc == locBody {
+		form, err := url.ParseQuery(body)
+		if err != nil {
+			return "", "", err
+		}
+		form.Set(c.param, value)
+		return c.url, form.Encode(), nil
+	}
+	if out, ok, err := editStructuredBody(c, body, func(string) string {
+		return value
+	}); ok {
+		return c.url, out, err
+	}
+
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", "", err
+	}
+	if rawPaths {
+		if q, ok := editRawQuery(u.RawQuery, c.param, func(string) string {
+			return url.QueryEscape(value)
+		}); ok {
+			return replaceRawQuery(c.url, q), body, nil
+		}
+	}
+	qs := u.Query()
+	qs.Set(c.param, value)
+	u.RawQuery = qs.Encode()
+	return u.String(), body, nil
+}
+
+// send issues a request for c to urlStr, using the method and headers of
+// its template when it has one, under -csrf, a fresh anti-CSRF token in
+// its form body and, for header checks, the tested header.
+func (c paramCheck) send(urlStr, body string) (*http.Response, error) {
+	if c.tmpl == nil {
+		return doInjectedRequest("GET", urlStr, nil, c.injectedHeader(), body, 3)
+	}
+	header := c.tmpl.Header
+	if c.tmpl.hasFormBody() {
+		var err error
+		body, header, err = csrf.refresh(c, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doInjectedRequest(c.tmpl.Method, urlStr, header, c.injectedHeader(), body, 3)
+}
+
+func checkAppend(c paramCheck, suffix string) (bool, bool, error) {
+	testURL, testBody, err := c.withSuffix(suffix)
 	if err != nil {
 		return false, false, err
 	}
+	p, err := probeAppend(c.withHeaderSuffix(suffix), testURL, testBody, suffix)
+	return p.reflected, p.isError, err
+}
+
+// appendProbe is the outcome of sending a probe for a parameter.
+type appendProbe struct {
+	reflected bool   // marker came back where it matters in a response that can render it
+	isError   bool   // a database error message showed up
+	database  string // the engine the message came from
+	blocked   bool   // the probe, unlike the base request, hit a block page
+}
+
+// probeAppend sends c's base request and then the test request to testURL
+// with testBody, and reports whether marker is reflected in the latter
+// where it matters, see reflectedWhereItMatters. Header values and request framing set on c for the test request are
+// left out of the base request.
+func probeAppend(c paramCheck, testURL, testBody, marker string) (appendProbe, error) {
+	var p appendProbe
+
+	// Perform base request for comparison
+	baseBody := ""
+	if c.tmpl != nil {
+		baseBody = c.tmpl.Body
+	}
+	base := c
+	base.headerValue = ""
+	base.chunked = false
+	baseResp, err := base.send(c.url, baseBody)
+	if err != nil {
+		return p, err
+	}
 	if baseResp.Body == nil {
-		return false, false, fmt.Errorf("nil base response body")
+		return p, fmt.Errorf("nil base response body")
 	}
 	defer baseResp.Body.Close()
 	baseStatusCode := baseResp.StatusCode
 
 	// Perform test request with suffix
-	resp, err := doRequestWithRetries("GET", u.String(), nil, 3)
+	resp, err := c.send(testURL, testBody)
 	if err != nil {
-		return false, false, err
+		return p, err
 	}
 	if resp.Body == nil {
-		return false, false, fmt.Errorf("nil response body")
+		return p, fmt.Errorf("nil response body")
 	}
 	defer resp.Body.Close()
 
 	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
 	if err != nil {
-		return false, false, err
+		return p, err
 	}
 
 	bodyStr := string(b)
-	isError := false
-	for _, patterns := range dbErrorPatterns {
-		for _, pattern := range patterns {
-			if strings.Contains(bodyStr, pattern) {
-				isError = true
-				break
-			}
-		}
-	}
+	p.database = matchDBError(bodyStr)
 	// Check if server error is false positive (if base request also returns 500)
 	if resp.StatusCode >= 500 && baseStatusCode >= 500 {
-		isError = false
+		p.database = ""
 	}
+	p.isError = p.database != ""
+	p.blocked = isBlockPage(resp.StatusCode, baseStatusCode, bodyStr)
 
 	if strings.HasPrefix(resp.Status, "3") {
-		return false, isError, nil
-	}
-	ct := resp.Header.Get("Content-Type")
-	if ct != "" && !strings.Contains(ct, "html") {
-		return false, isError, nil
+		return p, nil
 	}
-
-	if strings.Contains(bodyStr, suffix) {
-		return true, isError, nil
+	if !reflectableType(resp.Header.Get("Content-Type"), c.loc) {
+		return p, nil
 	}
+	p.reflected = reflectedWhereItMatters(bodyStr, marker)
+	return p, nil
+}
 
-	return false, isError, nil
+func doRequestWithRetries(method, urlStr string, header http.Header, body string, maxRetries int) (*http.Response, error) {
+	return doInjectedRequest(method, urlStr, header, nil, body, maxRetries)
 }
 
-func doRequestWithRetries(method, urlStr string, body io.Reader, maxRetries int) (*http.Response, error) {
+// doInjectedRequest is doRequestWithRetries with the headers in inject
+// replacing any the request would otherwise carry.
+func doInjectedRequest(method, urlStr string, header, inject http.Header, body string, maxRetries int) (*http.Response, error) {
+	if !scope.allows(urlStr) {
+		return nil, fmt.Errorf("%s is out of scope", urlStr)
+	}
 	var resp *http.Response
 	var err error
+	// The status and Location that looked like an expired session, to
+	// tell after logging in again whether it was one
+	relogged := false
+	var expiredStatus int
+	var expiredLocation string
 	for retries := 0; retries < maxRetries; retries++ {
-		req, err := http.NewRequest(method, urlStr, body)
+		var req *http.Request
+		req, err = http.NewRequest(method, urlStr, strings.NewReader(body))
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/80.0.3987.100 Safari/537.36")
+		req.Header = requestHeader(req.URL, header)
+		for k, vv := range inject {
+			req.Header[k] = vv
+		}
+		// net/http frames the body itself and ignores this header
+		if req.Header.Get("Transfer-Encoding") == "chunked" {
+			req.Header.Del("Transfer-Encoding")
+			req.TransferEncoding = []string{"chunked"}
+			req.ContentLength = -1
+		}
+		// net/http takes the Host header from req.Host and ignores it in
+		// req.Header, so a -host-header or -H "Host: ..." override has to
+		// be moved there.
+		if host := req.Header.Get("Host"); host != "" {
+			req.Host = host
+		}
+		if rawPaths {
+			setRawRequestURI(req, urlStr)
+		}
+		addCorrelationHeaders(req.Header)
+		if req.Header.Get("Accept-Encoding") == "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding())
+		}
 
-		resp, err = httpClient.Do(req)
+		pause()
+		hostRateLimit.wait(req.URL.Host)
+		rateLimit.wait()
+		gen := session.generation()
+		resp, err = digest.do(req, body)
+		if err == nil && resp != nil && !relogged && session.expired(req.URL, resp) {
+			// Log in again and resend, without counting it as a retry
+			resend, err := session.refresh(gen)
+			if err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("refreshing session: %v", err)
+			}
+			if resend {
+				resp.Body.Close()
+				relogged = true
+				expiredStatus, expiredLocation = resp.StatusCode, resp.Header.Get("Location")
+				retries--
+				continue
+			}
+		}
 		if err == nil && resp != nil {
+			if relogged && resp.StatusCode == expiredStatus && resp.Header.Get("Location") == expiredLocation {
+				// Logging in changed nothing: this is how the endpoint answers
+				session.markSteady(req.URL)
+			}
+			if err := decodeBody(resp); err != nil {
+				return nil, err
+			}
 			return resp, nil
 		}
 		time.Sleep(time.Second * time.Duration(retries+1))
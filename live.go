@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// liveSink streams findings as they are found to stderr or a socket, in
+// addition to the report written to -o.
+type liveSink struct {
+	resultSink
+	conn io.Closer
+}
+
+// newLiveSink opens dest, which is "stderr", tcp://host:port or
+// unix:///path/to/socket, and writes findings to it in a line-oriented
+// format: text, jsonl or tsv.
+func newLiveSink(dest, format string, opts formatOptions) (*liveSink, error) {
+	var w io.Writer
+	var conn io.Closer
+	switch {
+	case dest == "stderr":
+		w = os.Stderr
+	case strings.HasPrefix(dest, "tcp://"):
+		c, err := net.Dial("tcp", strings.TrimPrefix(dest, "tcp://"))
+		if err != nil {
+			return nil, err
+		}
+		w, conn = c, c
+	case strings.HasPrefix(dest, "unix://"):
+		c, err := net.Dial("unix", strings.TrimPrefix(dest, "unix://"))
+		if err != nil {
+			return nil, err
+		}
+		w, conn = c, c
+	default:
+		return nil, fmt.Errorf("live destination must be stderr, tcp://host:port or unix:///path")
+	}
+
+	if format != "text" && format != "jsonl" && format != "tsv" {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, fmt.Errorf("live format must be text, jsonl or tsv")
+	}
+	sink, _ := newFormatSink(format, w, opts)
+	return &liveSink{resultSink: sink, conn: conn}, nil
+}
+
+func (s *liveSink) close() error {
+	err := s.resultSink.close()
+	if s.conn != nil {
+		if cerr := s.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
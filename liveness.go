@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// livenessFilter drops checks whose origin does not answer HTTP at all.
+// Each origin (scheme, host and port) is probed once, no matter how many
+// URLs share it.
+type livenessFilter struct {
+	mu      sync.Mutex
+	origins map[string]*originProbe
+}
+
+type originProbe struct {
+	once  sync.Once
+	alive bool
+	err   error // why a dead origin did not answer
+}
+
+func newLivenessFilter() *livenessFilter {
+	return &livenessFilter{origins: make(map[string]*originProbe)}
+}
+
+// filter is a workerFunc passing c on only if its origin is alive.
+func (l *livenessFilter) filter(c paramCheck, output chan paramCheck) {
+	u, err := url.Parse(c.url)
+	if err != nil || u.Host == "" {
+		return
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	l.mu.Lock()
+	p, ok := l.origins[origin]
+	if !ok {
+		p = &originProbe{}
+		l.origins[origin] = p
+	}
+	l.mu.Unlock()
+
+	p.once.Do(func() {
+		// Any response at all, even an error status, means the host is up.
+		// The first URL seen is requested rather than the origin's root,
+		// which -scope may rule out.
+		resp, err := doRequestWithRetries("GET", c.url, nil, "", 1)
+		if err != nil {
+			p.err = err
+			return
+		}
+		resp.Body.Close()
+		p.alive = true
+	})
+	if p.alive {
+		output <- c
+	}
+}
+
+// counts returns how many probed origins were alive and dead.
+func (l *livenessFilter) counts() (alive, dead int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.origins {
+		if p.alive {
+			alive++
+		} else {
+			dead++
+		}
+	}
+	return alive, dead
+}
+
+// deadOrigin is an origin that did not answer the probe.
+type deadOrigin struct {
+	origin string
+	err    error
+}
+
+// dead returns the origins that did not answer, sorted.
+func (l *livenessFilter) dead() []deadOrigin {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []deadOrigin
+	for origin, p := range l.origins {
+		if !p.alive {
+			out = append(out, deadOrigin{origin, p.err})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].origin < out[j].origin })
+	return out
+}
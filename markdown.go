@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// markdownSink collects findings and writes them on close as a Markdown
+// report with one section per host, ready to paste into a submission.
+type markdownSink struct {
+	w       io.Writer
+	results []Result
+}
+
+func (s *markdownSink) write(r Result) error {
+	s.results = append(s.results, r)
+	return nil
+}
+
+func (s *markdownSink) close() error {
+	if len(s.results) == 0 {
+		return nil
+	}
+	byHost := make(map[string][]Result)
+	for _, r := range s.results {
+		host := resultHost(r)
+		byHost[host] = append(byHost[host], r)
+	}
+
+	var b strings.Builder
+	b.WriteString("# kxss findings\n")
+	for _, h := range summarizeHosts(s.results) {
+		fmt.Fprintf(&b, "\n## %s\n\n", h.Host)
+		fmt.Fprintf(&b, "%d findings across %d parameters", h.Findings, h.Params)
+		if h.SQLInjection > 0 {
+			fmt.Fprintf(&b, ", %d with database errors", h.SQLInjection)
+		}
+		b.WriteString(".\n")
+		for _, r := range byHost[h.Host] {
+			fmt.Fprintf(&b, "\n### `%s` (%s)\n\n", r.Param, r.Location)
+			fmt.Fprintf(&b, "- URL: <%s>\n", r.URL)
+			if r.Severity != "" {
+				fmt.Fprintf(&b, "- Severity: %s\n", r.Severity)
+			}
+			fmt.Fprintf(&b, "- Unfiltered: %s\n", markdownChars(r.Unfiltered))
+			if len(r.Context) > 0 {
+				fmt.Fprintf(&b, "- Context: %s\n", strings.Join(r.Context, ", "))
+			}
+			if r.SQLInjection && r.Database != "" {
+				fmt.Fprintf(&b, "- Database error message in response (%s)\n", r.Database)
+			} else if r.SQLInjection {
+				b.WriteString("- Database error message in response\n")
+			}
+			if r.CRLFInjection {
+				fmt.Fprintf(&b, "- CRLF injection: `%s` added a response header\n", strconv.Quote(r.Payload))
+			}
+			if r.BlindSQLInjection == sqliTime {
+				fmt.Fprintf(&b, "- Blind SQL injection (%s): `%s` delayed the response by %s, repeatedly\n", r.Database, r.Payload, injectionDelay)
+			}
+			if r.BlindSQLInjection == sqliBoolean && len(r.Payloads) == 2 {
+				fmt.Fprintf(&b, "- Blind SQL injection (boolean): `%s` left the page as it was, `%s` changed it\n", r.Payloads[0], r.Payloads[1])
+			}
+			if r.NoSQLInjection == nosqlError {
+				fmt.Fprintf(&b, "- NoSQL injection (error): `%s` drew a database error\n", r.Payload)
+			}
+			if (r.NoSQLInjection == nosqlOperator || r.NoSQLInjection == nosqlJavaScript) && len(r.Payloads) == 2 {
+				fmt.Fprintf(&b, "- NoSQL injection (%s): `%s` left the page as it was, `%s` changed it\n", r.NoSQLInjection, r.Payloads[0], r.Payloads[1])
+			}
+			if r.CommandInjection {
+				fmt.Fprintf(&b, "- Command injection: `%s` delayed the response by %s, repeatedly\n", strconv.Quote(r.Payload), injectionDelay)
+			}
+			if r.PathTraversal {
+				fmt.Fprintf(&b, "- Path traversal: `%s` returned the file\n", strconv.Quote(r.Payload))
+			}
+			if len(r.SSRF) > 0 {
+				fmt.Fprintf(&b, "- SSRF: the server fetched <%s> (%s)\n", r.Payload, strings.Join(r.SSRF, ", "))
+			}
+			if r.XXE != "" {
+				fmt.Fprintf(&b, "- XXE (%s): the parser resolved an external entity\n", xxeDetail(r))
+			}
+			if r.SSTI {
+				fmt.Fprintf(&b, "- Template injection: `%s` was evaluated (%s)\n", r.Payload, r.TemplateEngine)
+			}
+			for _, e := range r.Evasions {
+				fmt.Fprintf(&b, "- %s got past a block page with the %s evasion\n", markdownChars([]string{e.Char}), e.Technique)
+			}
+			for _, e := range r.Decoded {
+				fmt.Fprintf(&b, "- %s was filtered in plain form but came back decoded from its %s form\n", markdownChars([]string{e.Char}), e.Technique)
+			}
+			if r.Verified {
+				fmt.Fprintf(&b, "- Verified: `%s` came back as markup\n", r.Payload)
+			}
+			if r.PoC != "" {
+				fmt.Fprintf(&b, "- Reproduce: <%s>\n", r.PoC)
+			}
+			if r.Evidence != "" {
+				fence := "```"
+				for strings.Contains(r.Evidence, fence) {
+					fence += "`"
+				}
+				fmt.Fprintf(&b, "\n%shtml\n%s\n%s\n", fence, r.Evidence, fence)
+			}
+		}
+	}
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
+
+func markdownChars(chars []string) string {
+	if len(chars) == 0 {
+		return "none"
+	}
+	quoted := make([]string, len(chars))
+	for i, c := range chars {
+		if c == "`" {
+			quoted[i] = "`` ` ``"
+		} else {
+			quoted[i] = "`" + c + "`"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// methodExpander returns the workerFunc for -method: "POST" sends the
+// query parameters of plain URLs as a form body instead, and "BOTH" tests
+// them both ways. Checks that already carry a request template keep their
+// own method.
+func methodExpander(method string) (workerFunc, error) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return nil, nil
+	case "POST":
+		return func(c paramCheck, output chan paramCheck) {
+			if post, ok := postVariant(c); ok {
+				output <- post
+				return
+			}
+			output <- c
+		}, nil
+	case "BOTH":
+		return func(c paramCheck, output chan paramCheck) {
+			output <- c
+			if post, ok := postVariant(c); ok {
+				output <- post
+			}
+		}, nil
+	}
+	return nil, fmt.Errorf("method must be GET, POST or BOTH")
+}
+
+// postVariant returns c as a form-urlencoded POST to the same URL without
+// its query string, or false when c has a template or no query.
+func postVariant(c paramCheck) (paramCheck, bool) {
+	if c.tmpl != nil {
+		return paramCheck{}, false
+	}
+	u, err := url.Parse(c.url)
+	if err != nil || u.RawQuery == "" {
+		return paramCheck{}, false
+	}
+	body := u.Query().Encode()
+	u.RawQuery = ""
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return paramCheck{url: u.String(), tmpl: &requestTemplate{
+		Method: "POST",
+		Header: header,
+		Body:   body,
+	}}, true
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+// multipartField is a text field value, or the filename of a file field,
+// in a multipart/form-data body.
+type multipartField struct {
+	name       string
+	filename   bool
+	value      string
+	start, end int // raw bytes of the value in the body
+}
+
+var filenamePattern = regexp.MustCompile(`(?i)\bfilename="([^"]*)"`)
+
+// multipartFields lists the text fields and filenames of body, leaving
+// file contents alone. Parts may end lines with CRLF or bare LF.
+func multipartFields(body, boundary string) []multipartField {
+	delim := "--" + boundary
+	var out []multipartField
+	pos := strings.Index(body, delim)
+	for pos >= 0 {
+		partStart := pos + len(delim)
+		if strings.HasPrefix(body[partStart:], "--") {
+			break
+		}
+		next := strings.Index(body[partStart:], delim)
+		if next < 0 {
+			break
+		}
+		partEnd := partStart + next
+		part := body[partStart:partEnd]
+		pos = partEnd
+
+		headerStart := len(part) - len(strings.TrimLeft(part, "\r\n"))
+		sep, sepLen := strings.Index(part, "\r\n\r\n"), 4
+		if sep < 0 {
+			sep, sepLen = strings.Index(part, "\n\n"), 2
+		}
+		if sep < 0 || sep < headerStart {
+			continue
+		}
+		header := part[headerStart:sep]
+		contentStart := sep + sepLen
+		content := strings.TrimSuffix(strings.TrimSuffix(part[contentStart:], "\n"), "\r")
+
+		var disposition string
+		headerOffset := headerStart
+		for _, line := range strings.SplitAfter(header, "\n") {
+			name, value, ok := strings.Cut(line, ":")
+			if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Disposition") {
+				disposition = strings.TrimSpace(value)
+				break
+			}
+			headerOffset += len(line)
+		}
+		_, params, err := mime.ParseMediaType(disposition)
+		if err != nil || params["name"] == "" {
+			continue
+		}
+
+		if _, isFile := params["filename"]; isFile {
+			line := header[headerOffset-headerStart:]
+			if m := filenamePattern.FindStringSubmatchIndex(line); m != nil {
+				base := partStart + headerOffset
+				out = append(out, multipartField{name: params["name"], filename: true, value: line[m[2]:m[3]], start: base + m[2], end: base + m[3]})
+			}
+			continue
+		}
+		base := partStart + contentStart
+		out = append(out, multipartField{name: params["name"], value: content, start: base, end: base + len(content)})
+	}
+	return out
+}
+
+// multipartBoundary returns the boundary of a multipart/form-data
+// Content-Type, or "".
+func multipartBoundary(ct string) string {
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil || mediaType != "multipart/form-data" {
+		return ""
+	}
+	return params["boundary"]
+}
+
+// multipartContentType recovers the Content-Type of a multipart body from
+// its first delimiter line.
+func multipartContentType(body string) string {
+	line, _, _ := strings.Cut(strings.TrimLeft(body, "\r\n"), "\n")
+	boundary, ok := strings.CutPrefix(strings.TrimSuffix(line, "\r"), "--")
+	if !ok || boundary == "" {
+		return ""
+	}
+	return mime.FormatMediaType("multipart/form-data", map[string]string{"boundary": boundary})
+}
+
+func multipartParams(body, boundary string) []bodyParam {
+	var out []bodyParam
+	seen := make(map[string]bool)
+	for _, f := range multipartFields(body, boundary) {
+		loc := locMultipart
+		if f.filename {
+			loc = locMultipartFile
+		}
+		if seen[string(loc)+f.name] {
+			continue
+		}
+		seen[string(loc)+f.name] = true
+		out = append(out, bodyParam{name: f.name, loc: loc, value: f.value})
+	}
+	return out
+}
+
+// editMultipart rewrites the value of field name, or its filename, with
+// edit. Filenames get quotes and line breaks percent-encoded as browsers
+// send them, so that the part headers still parse.
+func editMultipart(body, boundary, name string, filename bool, edit func(string) string) (string, error) {
+	for _, f := range multipartFields(body, boundary) {
+		if f.name != name || f.filename != filename {
+			continue
+		}
+		s := edit(f.value)
+		if filename {
+			s = strings.NewReplacer(`"`, "%22", "\r", "%0D", "\n", "%0A").Replace(s)
+		}
+		return body[:f.start] + s + body[f.end:], nil
+	}
+	return "", fmt.Errorf("field %s not found in multipart body", name)
+}
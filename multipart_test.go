@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+// multipartBody joins lines with eol into a body with boundary "b".
+func multipartBody(eol string, lines ...string) string {
+	return strings.Join(lines, eol) + eol
+}
+
+var multipartLines = []string{
+	"--b",
+	`Content-Disposition: form-data; name="user"`,
+	"",
+	"bob",
+	"--b",
+	`Content-Disposition: form-data; name="avatar"; filename="me.png"`,
+	"Content-Type: image/png",
+	"",
+	"PNGDATA",
+	"--b",
+	`Content-Disposition: form-data; name="note"`,
+	"",
+	"line one",
+	"line two",
+	"--b--",
+}
+
+func TestMultipartFields(t *testing.T) {
+	for _, eol := range []string{"\r\n", "\n"} {
+		body := multipartBody(eol, multipartLines...)
+		got := multipartFields(body, "b")
+		want := []multipartField{
+			{name: "user", value: "bob"},
+			{name: "avatar", filename: true, value: "me.png"},
+			{name: "note", value: "line one" + eol + "line two"},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("eol %q: got %d fields, want %d", eol, len(got), len(want))
+		}
+		for i, f := range got {
+			if f.name != want[i].name || f.filename != want[i].filename || f.value != want[i].value {
+				t.Errorf("eol %q: field %d = %+v, want %+v", eol, i, f, want[i])
+			}
+			if body[f.start:f.end] != f.value {
+				t.Errorf("eol %q: field %s spans %q, not its value", eol, f.name, body[f.start:f.end])
+			}
+		}
+	}
+}
+
+func TestMultipartBoundary(t *testing.T) {
+	tests := []struct {
+		ct, want string
+	}{
+		{"multipart/form-data; boundary=b", "b"},
+		{`multipart/form-data; boundary="----x y"`, "----x y"},
+		{"multipart/mixed; boundary=b", ""},
+		{"application/json", ""},
+	}
+	for _, tt := range tests {
+		if got := multipartBoundary(tt.ct); got != tt.want {
+			t.Errorf("multipartBoundary(%q) = %q, want %q", tt.ct, got, tt.want)
+		}
+	}
+	body := multipartBody("\r\n", multipartLines...)
+	if got := multipartBoundary(multipartContentType(body)); got != "b" {
+		t.Errorf("boundary recovered from the body = %q, want b", got)
+	}
+}
+
+// TestEditMultipartRoundTrip edits each field and reads the body back
+// with mime/multipart, which must see the edit and every other part
+// unchanged.
+func TestEditMultipartRoundTrip(t *testing.T) {
+	const suffix = "\"<x>\r\nmore"
+	tests := []struct {
+		name      string
+		filename  bool
+		wantValue string
+	}{
+		{"user", false, "bob" + suffix},
+		{"note", false, "line one\r\nline two" + suffix},
+		{"avatar", true, "me.png%22<x>%0D%0Amore"},
+	}
+	body := multipartBody("\r\n", multipartLines...)
+	for _, tt := range tests {
+		out, err := editMultipart(body, "b", tt.name, tt.filename, func(s string) string { return s + suffix })
+		if err != nil {
+			t.Errorf("editMultipart(%s): %v", tt.name, err)
+			continue
+		}
+		got := make(map[string]string)
+		r := multipart.NewReader(strings.NewReader(out), "b")
+		for {
+			p, err := r.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("editMultipart(%s) broke the body: %v\n%s", tt.name, err, out)
+			}
+			b, _ := io.ReadAll(p)
+			got[p.FormName()] = string(b)
+			if p.FileName() != "" {
+				got[p.FormName()+" filename"] = p.FileName()
+			}
+		}
+		key := tt.name
+		if tt.filename {
+			key += " filename"
+		}
+		if got[key] != tt.wantValue {
+			t.Errorf("editMultipart(%s): read back %q, want %q", tt.name, got[key], tt.wantValue)
+		}
+		for name, want := range map[string]string{"user": "bob", "note": "line one\r\nline two", "avatar": "PNGDATA"} {
+			if name != tt.name && got[name] != want {
+				t.Errorf("editMultipart(%s) changed %s to %q", tt.name, name, got[name])
+			}
+		}
+	}
+}
+
+func TestEditMultipartMissing(t *testing.T) {
+	body := multipartBody("\r\n", multipartLines...)
+	if _, err := editMultipart(body, "b", "user", true, func(s string) string { return s }); err == nil {
+		t.Error("editing the filename of a text field succeeded")
+	}
+	if _, err := editMultipart(body, "b", "nope", false, func(s string) string { return s }); err == nil {
+		t.Error("editing a missing field succeeded")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/xml"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// nmapRun covers the parts of nmap -oX and masscan -oX output used to
+// find web services.
+type nmapRun struct {
+	Hosts []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []nmapPort `xml:"ports>port"`
+}
+
+type nmapPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State string `xml:"state,attr"`
+	} `xml:"state"`
+	Service struct {
+		Name   string `xml:"name,attr"`
+		Tunnel string `xml:"tunnel,attr"`
+	} `xml:"service"`
+}
+
+// Ports treated as web services when the scan has no service detection,
+// as is always the case for masscan.
+var (
+	httpPorts  = map[int]bool{80: true, 81: true, 591: true, 3000: true, 5000: true, 8000: true, 8008: true, 8080: true, 8081: true, 8888: true}
+	httpsPorts = map[int]bool{443: true, 4443: true, 8443: true, 9443: true}
+)
+
+// readNmapBaseURLs returns a base URL such as https://host:8443/ for every
+// open web port in an nmap or masscan XML report.
+func readNmapBaseURLs(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var run nmapRun
+	if err := xml.NewDecoder(file).Decode(&run); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, h := range run.Hosts {
+		host := nmapHostName(h)
+		if host == "" {
+			continue
+		}
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			scheme, ok := webScheme(p)
+			if !ok {
+				continue
+			}
+			u := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(p.PortID)) + "/"
+			if !seen[u] {
+				seen[u] = true
+				out = append(out, u)
+			}
+		}
+	}
+	return out, nil
+}
+
+// nmapHostName prefers a scanned hostname over the IP address, so that
+// name-based virtual hosts are reached.
+func nmapHostName(h nmapHost) string {
+	for _, n := range h.Hostnames {
+		if n.Name != "" {
+			return n.Name
+		}
+	}
+	for _, a := range h.Addresses {
+		if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+			return a.Addr
+		}
+	}
+	return ""
+}
+
+// webScheme decides whether a port speaks HTTP and which scheme to use,
+// from detected service information when present and the port number
+// otherwise.
+func webScheme(p nmapPort) (string, bool) {
+	name := p.Service.Name
+	switch {
+	case name == "https" || (strings.Contains(name, "http") && p.Service.Tunnel == "ssl"):
+		return "https", true
+	case strings.Contains(name, "http"):
+		return "http", true
+	case name != "" && name != "unknown":
+		return "", false
+	case httpsPorts[p.PortID]:
+		return "https", true
+	case httpPorts[p.PortID]:
+		return "http", true
+	}
+	return "", false
+}
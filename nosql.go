@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Techniques in Result.NoSQLInjection.
+const (
+	nosqlError      = "error"      // a query operator or quote drew a database error
+	nosqlOperator   = "operator"   // $eq and $ne on the same value select different records
+	nosqlJavaScript = "javascript" // a condition appended to the value is evaluated, as in $where
+)
+
+// nosqlErrors are only found in messages from MongoDB, its drivers and
+// Mongoose.
+var nosqlErrors = []string{
+	"MongoError",
+	"MongoServerError",
+	"unknown operator",
+	"unknown top level operator",
+	"$where",
+	"BSONTypeError",
+	"Cast to ObjectId failed",
+	"CastError",
+}
+
+// nosqlErrorSuffix is appended to the tested value, to break out of the
+// string in a JavaScript condition.
+const nosqlErrorSuffix = `'"\`
+
+// nosqlBadOperator does not exist, so a query object using it fails.
+const nosqlBadOperator = "$kxss"
+
+// nosqlJSPairs append a condition to the value after single and double
+// quotes; each format takes two numbers, different for the variant that
+// leaves the query as it was and equal for the one that matches
+// everything.
+var nosqlJSPairs = []sqlBooleanPair{
+	{"'||'%d'=='%d"},
+	{"\"||\"%d\"==\"%d"},
+}
+
+var nosqlCheck = injectionCheck{
+	label:  "NoSQL Injection",
+	found:  func(r Result) bool { return r.NoSQLInjection != "" },
+	detail: func(r Result) string { return r.NoSQLInjection },
+	test:   testNoSQL,
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, nosqlCheck)
+}
+
+// withOperator returns the URL and body of c with its value replaced by a
+// query object {op: value}: param[op]=value in a query string or form body,
+// and the object itself in a JSON body. A []string value becomes an array.
+// ok is false where an object cannot be written.
+func withOperator(c paramCheck, op string, value any) (urlStr, body string, ok bool, err error) {
+	if c.tmpl != nil {
+		body = c.tmpl.Body
+	}
+	switch c.loc {
+	case locQuery, locBody:
+		key := c.param + "[" + op + "]"
+		values := []string{}
+		switch v := value.(type) {
+		case string:
+			values = append(values, v)
+		case []string:
+			key += "[]"
+			values = v
+		}
+		set := func(q url.Values) string {
+			q.Del(c.param)
+			q[key] = values
+			return q.Encode()
+		}
+		if c.loc == locBody {
+			form, err := url.ParseQuery(body)
+			if err != nil {
+				return "", "", false, err
+			}
+			return c.url, set(form), true, nil
+		}
+		u, err := url.Parse(c.url)
+		if err != nil {
+			return "", "", false, err
+		}
+		u.RawQuery = set(u.Query())
+		return u.String(), body, true, nil
+	case locJSON:
+		// editJSON only writes strings: write a placeholder and swap the
+		// encoded object in for it
+		placeholder := "kxss-nosql-" + reflectionCanary
+		out, err := editJSON(body, c.param, false, func(string) string { return placeholder })
+		if err != nil {
+			return "", "", false, err
+		}
+		obj, err := encodeJSON(map[string]any{op: value})
+		if err != nil {
+			return "", "", false, err
+		}
+		return c.url, strings.Replace(out, `"`+placeholder+`"`, obj, 1), true, nil
+	}
+	return "", "", false, nil
+}
+
+// sendOperator sends c with its value replaced by {op: value}, see
+// withOperator.
+func sendOperator(c paramCheck, op string, value any) (injectedResponse, bool, error) {
+	urlStr, body, ok, err := withOperator(c, op, value)
+	if err != nil || !ok {
+		return injectedResponse{}, false, err
+	}
+	resp, err := sendTest(c, urlStr, body)
+	return resp, true, err
+}
+
+// testNoSQL looks for MongoDB-style injection in c's parameter, reporting
+// the first technique that works:
+//
+//   - nosqlErrorSuffix appended, or nosqlBadOperator as a query object,
+//     drawing one of nosqlErrors that the unmodified page does not show;
+//   - {"$eq": value} looking like the unmodified page where {"$ne": value}
+//     does not, confirmed with $in and $nin;
+//   - each of nosqlJSPairs, where the false condition looks like the
+//     unmodified page and the true one does not, twice with different
+//     numbers.
+func testNoSQL(c paramCheck, r *Result) error {
+	if c.loc == locHeader || c.loc == locJSONKey {
+		return nil
+	}
+	base, stability, stable, err := stableBaseline(c)
+	if err != nil {
+		return err
+	}
+	value := c.value()
+
+	found := func(resp injectedResponse, payload string) bool {
+		for _, e := range nosqlErrors {
+			if strings.Contains(resp.body, e) && !strings.Contains(base.body, e) {
+				r.NoSQLInjection = nosqlError
+				r.Payload = payload
+				r.Curl = resp.curl
+				r.Evidence = snippetAround(resp.body, e, evidenceContext)
+				return true
+			}
+		}
+		return false
+	}
+	resp, err := sendInjected(c, nosqlErrorSuffix)
+	if err != nil || found(resp, nosqlErrorSuffix) {
+		return err
+	}
+	resp, ok, err := sendOperator(c, nosqlBadOperator, value)
+	if err != nil || ok && found(resp, fmt.Sprintf(`{"%s": %q}`, nosqlBadOperator, value)) {
+		return err
+	}
+	if !stable {
+		return nil
+	}
+
+	// operators reports whether {same: v} looks like the unmodified page
+	// and {changed: v} does not
+	operators := func(same, changed string, v any) (bool, injectedResponse, error) {
+		resp, ok, err := sendOperator(c, same, v)
+		if err != nil || !ok || !resembles(base, resp, "", stability-maxDivergence/2) {
+			return false, resp, err
+		}
+		resp, _, err = sendOperator(c, changed, v)
+		if err != nil {
+			return false, resp, err
+		}
+		return !resembles(base, resp, "", stability-maxDivergence), resp, nil
+	}
+	ok, resp, err = operators("$eq", "$ne", value)
+	if err != nil {
+		return err
+	}
+	if ok {
+		ok, _, err = operators("$in", "$nin", []string{value})
+		if err != nil {
+			return err
+		}
+		if ok {
+			r.NoSQLInjection = nosqlOperator
+			r.Payload = fmt.Sprintf(`{"$ne": %q}`, value)
+			r.Payloads = []string{fmt.Sprintf(`{"$eq": %q}`, value), r.Payload}
+			r.Curl = resp.curl
+			return nil
+		}
+	}
+
+	differs := func(p sqlBooleanPair, n int) (bool, injectedResponse, error) {
+		t, f := p.variants(n)
+		falsy, err := sendInjected(c, f)
+		if err != nil || !resembles(base, falsy, f, stability-maxDivergence/2) {
+			return false, falsy, err
+		}
+		truthy, err := sendInjected(c, t)
+		if err != nil {
+			return false, truthy, err
+		}
+		return !resembles(base, truthy, t, stability-maxDivergence), truthy, nil
+	}
+	for _, p := range nosqlJSPairs {
+		ok, _, err := differs(p, 1)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		ok, truthy, err := differs(p, 7)
+		if err != nil {
+			return err
+		}
+		if ok {
+			t, f := p.variants(7)
+			r.NoSQLInjection = nosqlJavaScript
+			r.Payload = t
+			r.Payloads = []string{f, t}
+			r.Curl = truthy.curl
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// notifyConfig is the on-disk layout of a --notify-config file. Only the
+// sections for services passed to --notify need to be filled in:
+//
+//	slack:
+//	  webhook_url: https://hooks.slack.com/services/...
+//	discord:
+//	  webhook_url: https://discord.com/api/webhooks/...
+//	telegram:
+//	  bot_token: "123456:ABC..."
+//	  chat_id: "-1001234567890"
+type notifyConfig struct {
+	Slack struct {
+		WebhookURL string `yaml:"webhook_url"`
+	} `yaml:"slack"`
+	Discord struct {
+		WebhookURL string `yaml:"webhook_url"`
+	} `yaml:"discord"`
+	Telegram struct {
+		BotToken string `yaml:"bot_token"`
+		ChatID   string `yaml:"chat_id"`
+	} `yaml:"telegram"`
+}
+
+func loadNotifyConfig(path string) (*notifyConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg notifyConfig
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// newNotifySink returns a sink that posts each finding to the named chat
+// service using the matching section of cfg.
+func newNotifySink(service string, cfg *notifyConfig) (resultSink, error) {
+	switch service {
+	case "slack":
+		if cfg.Slack.WebhookURL == "" {
+			return nil, fmt.Errorf("slack needs webhook_url")
+		}
+		return startWebhookSink(cfg.Slack.WebhookURL, make(http.Header), func(r Result) ([]byte, error) {
+			return json.Marshal(map[string]string{"text": notifyMessage(r)})
+		}), nil
+	case "discord":
+		if cfg.Discord.WebhookURL == "" {
+			return nil, fmt.Errorf("discord needs webhook_url")
+		}
+		return startWebhookSink(cfg.Discord.WebhookURL, make(http.Header), func(r Result) ([]byte, error) {
+			return json.Marshal(map[string]string{"content": notifyMessage(r)})
+		}), nil
+	case "telegram":
+		if cfg.Telegram.BotToken == "" || cfg.Telegram.ChatID == "" {
+			return nil, fmt.Errorf("telegram needs bot_token and chat_id")
+		}
+		url := "https://api.telegram.org/bot" + cfg.Telegram.BotToken + "/sendMessage"
+		return startWebhookSink(url, make(http.Header), func(r Result) ([]byte, error) {
+			return json.Marshal(map[string]any{
+				"chat_id":                  cfg.Telegram.ChatID,
+				"text":                     notifyMessage(r),
+				"disable_web_page_preview": true,
+			})
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown notification service %s", service)
+}
+
+func notifyMessage(r Result) string {
+	var b strings.Builder
+	b.WriteString("kxss")
+	if r.Severity != "" {
+		fmt.Fprintf(&b, " [%s]", r.Severity)
+	}
+	b.WriteString(": ")
+	if r.SQLInjection {
+		b.WriteString("possible SQL injection, ")
+	}
+	if r.CRLFInjection {
+		b.WriteString("CRLF injection, ")
+	}
+	if r.BlindSQLInjection != "" {
+		fmt.Fprintf(&b, "blind SQL injection (%s), ", blindSQLiDetail(r))
+	}
+	if r.NoSQLInjection != "" {
+		fmt.Fprintf(&b, "NoSQL injection (%s), ", r.NoSQLInjection)
+	}
+	if r.CommandInjection {
+		b.WriteString("command injection, ")
+	}
+	if r.PathTraversal {
+		b.WriteString("path traversal, ")
+	}
+	if len(r.SSRF) > 0 {
+		fmt.Fprintf(&b, "SSRF (%s), ", strings.Join(r.SSRF, ", "))
+	}
+	if r.XXE != "" {
+		fmt.Fprintf(&b, "XXE (%s), ", xxeDetail(r))
+	}
+	if r.SSTI {
+		fmt.Fprintf(&b, "template injection (%s), ", r.TemplateEngine)
+	}
+	fmt.Fprintf(&b, "param %s (%s) on %s", r.Param, r.Location, r.URL)
+	if len(r.Unfiltered) > 0 {
+		fmt.Fprintf(&b, "\nunfiltered: %s", strings.Join(r.Unfiltered, " "))
+	}
+	if r.PoC != "" {
+		fmt.Fprintf(&b, "\npoc: %s", r.PoC)
+	}
+	return b.String()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec covers the parts of OpenAPI 3 and Swagger 2 documents (YAML
+// or JSON) needed to enumerate endpoints and their parameters.
+type openAPISpec struct {
+	Host     string   `yaml:"host"`
+	BasePath string   `yaml:"basePath"`
+	Schemes  []string `yaml:"schemes"`
+	Servers  []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths      map[string]openAPIPathItem `yaml:"paths"`
+	Parameters map[string]openAPIParam    `yaml:"parameters"`
+	Components struct {
+		Parameters map[string]openAPIParam  `yaml:"parameters"`
+		Schemas    map[string]openAPISchema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type openAPIPathItem struct {
+	Parameters []openAPIParam    `yaml:"parameters"`
+	Get        *openAPIOperation `yaml:"get"`
+	Post       *openAPIOperation `yaml:"post"`
+	Put        *openAPIOperation `yaml:"put"`
+	Patch      *openAPIOperation `yaml:"patch"`
+	Delete     *openAPIOperation `yaml:"delete"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParam `yaml:"parameters"`
+	RequestBody struct {
+		Content map[string]struct {
+			Schema openAPISchema `yaml:"schema"`
+		} `yaml:"content"`
+	} `yaml:"requestBody"`
+}
+
+type openAPIParam struct {
+	Ref     string        `yaml:"$ref"`
+	Name    string        `yaml:"name"`
+	In      string        `yaml:"in"`
+	Example interface{}   `yaml:"example"`
+	Schema  openAPISchema `yaml:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                   `yaml:"$ref"`
+	Example    interface{}              `yaml:"example"`
+	Default    interface{}              `yaml:"default"`
+	Properties map[string]openAPISchema `yaml:"properties"`
+	XML        struct {
+		Name string `yaml:"name"`
+	} `yaml:"xml"`
+}
+
+// readOpenAPIChecks builds one check per documented operation that takes
+// query or form parameters or an XML body. Tested parameters get
+// placeholderValue, path parameters their example value (or 1). base
+// overrides the server URL in the spec and resolves relative ones.
+func readOpenAPIChecks(path, base string) ([]paramCheck, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec openAPISpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+
+	server, err := spec.serverURL(base)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort paths so the generated checks come out in a stable order
+	paths := make([]string, 0, len(spec.Paths))
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	out := make([]paramCheck, 0)
+	for _, p := range paths {
+		item := spec.Paths[p]
+		ops := []struct {
+			method string
+			op     *openAPIOperation
+		}{
+			{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put},
+			{"PATCH", item.Patch}, {"DELETE", item.Delete},
+		}
+		for _, o := range ops {
+			if o.op == nil {
+				continue
+			}
+			if c, ok := spec.operationCheck(server, p, o.method, item.Parameters, o.op); ok {
+				out = append(out, c)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *openAPISpec) serverURL(base string) (*url.URL, error) {
+	if base == "" {
+		switch {
+		case len(s.Servers) > 0:
+			base = s.Servers[0].URL
+		case s.Host != "":
+			scheme := "https"
+			if len(s.Schemes) > 0 {
+				scheme = s.Schemes[0]
+			}
+			base = scheme + "://" + s.Host + s.BasePath
+		}
+	} else if len(s.Servers) > 0 && !strings.Contains(s.Servers[0].URL, "://") {
+		// A relative server URL such as /api/v1 is resolved against base
+		base = strings.TrimRight(base, "/") + s.Servers[0].URL
+	} else if s.BasePath != "" {
+		base = strings.TrimRight(base, "/") + s.BasePath
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("spec has no absolute server URL, set one with -openapi-base")
+	}
+	return u, nil
+}
+
+func (s *openAPISpec) operationCheck(server *url.URL, path, method string, shared []openAPIParam, op *openAPIOperation) (paramCheck, bool) {
+	query := url.Values{}
+	form := url.Values{}
+	for _, p := range append(append([]openAPIParam{}, shared...), op.Parameters...) {
+		p = s.resolveParam(p)
+		switch p.In {
+		case "query":
+			query.Set(p.Name, placeholderValue)
+		case "formData":
+			form.Set(p.Name, placeholderValue)
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(exampleValue(p)))
+		}
+	}
+	if media, ok := op.RequestBody.Content["application/x-www-form-urlencoded"]; ok {
+		for name := range s.resolveSchema(media.Schema).Properties {
+			form.Set(name, placeholderValue)
+		}
+	}
+	var xmlBody, xmlType string
+	for _, ct := range []string{"application/xml", "text/xml"} {
+		if media, ok := op.RequestBody.Content[ct]; ok && len(form) == 0 {
+			xmlBody, xmlType = s.xmlExample(media.Schema), ct
+			break
+		}
+	}
+	if len(query) == 0 && len(form) == 0 && xmlBody == "" {
+		return paramCheck{}, false
+	}
+
+	// path holds escaped parameter values: keep it as the raw path so that
+	// they are not escaped a second time
+	u := *server
+	u.RawPath = strings.TrimRight(server.EscapedPath(), "/") + path
+	unescaped, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return paramCheck{}, false
+	}
+	u.Path = unescaped
+	u.RawQuery = query.Encode()
+
+	c := paramCheck{url: u.String()}
+	if method != "GET" || len(form) > 0 || xmlBody != "" {
+		c.tmpl = &requestTemplate{Method: method, Header: make(http.Header)}
+		switch {
+		case len(form) > 0:
+			c.tmpl.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			c.tmpl.Body = form.Encode()
+		case xmlBody != "":
+			c.tmpl.Header.Set("Content-Type", xmlType)
+			c.tmpl.Body = xmlBody
+		}
+	}
+	return c, true
+}
+
+// xmlExample renders an XML request body for schema with placeholderValue
+// in every property. The root element is named after the schema.
+func (s *openAPISpec) xmlExample(schema openAPISchema) string {
+	root := "request"
+	if schema.Ref != "" {
+		root = schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]
+	}
+	schema = s.resolveSchema(schema)
+	if schema.XML.Name != "" {
+		root = schema.XML.Name
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("<" + root + ">")
+	for _, name := range names {
+		if n := schema.Properties[name].XML.Name; n != "" {
+			name = n
+		}
+		b.WriteString("<" + name + ">" + placeholderValue + "</" + name + ">")
+	}
+	b.WriteString("</" + root + ">")
+	return b.String()
+}
+
+// resolveParam follows a local #/components/parameters or #/parameters
+// reference.
+func (s *openAPISpec) resolveParam(p openAPIParam) openAPIParam {
+	if p.Ref == "" {
+		return p
+	}
+	name := p.Ref[strings.LastIndex(p.Ref, "/")+1:]
+	if r, ok := s.Components.Parameters[name]; ok {
+		return r
+	}
+	if r, ok := s.Parameters[name]; ok {
+		return r
+	}
+	return p
+}
+
+// resolveSchema follows a local #/components/schemas reference.
+func (s *openAPISpec) resolveSchema(schema openAPISchema) openAPISchema {
+	if schema.Ref == "" {
+		return schema
+	}
+	name := schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]
+	if r, ok := s.Components.Schemas[name]; ok {
+		return r
+	}
+	return schema
+}
+
+func exampleValue(p openAPIParam) string {
+	for _, v := range []interface{}{p.Example, p.Schema.Example, p.Schema.Default} {
+		if v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return "1"
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// formatExtensions lists the formats newFormatSink knows, with the file
+// extension used for each under -output-dir.
+var formatExtensions = map[string]string{
+	"text":     ".txt",
+	"json":     ".json",
+	"jsonl":    ".jsonl",
+	"tsv":      ".tsv",
+	"sarif":    ".sarif",
+	"markdown": ".md",
+	"xml":      ".xml",
+	"burp":     ".xml",
+	"template": ".txt",
+}
+
+// formatOptions carries the flags that tweak individual output formats.
+type formatOptions struct {
+	delim    string             // field separator for tsv
+	curl     bool               // show the reproducing curl command in text output
+	template *template.Template // layout for the template format
+}
+
+// newFormatSink returns a sink writing format to w.
+func newFormatSink(format string, w io.Writer, opts formatOptions) (resultSink, error) {
+	switch format {
+	case "text":
+		return &textSink{w: w, curl: opts.curl}, nil
+	case "json":
+		return &jsonSink{w: w, indent: true}, nil
+	case "jsonl":
+		return &jsonSink{w: w}, nil
+	case "tsv":
+		return &delimitedSink{w: w, delim: opts.delim}, nil
+	case "sarif":
+		return &sarifSink{w: w}, nil
+	case "markdown":
+		return &markdownSink{w: w}, nil
+	case "xml":
+		return &xmlSink{w: w}, nil
+	case "burp":
+		return &burpIssueSink{w: w}, nil
+	case "template":
+		if opts.template == nil {
+			return nil, fmt.Errorf("-format template needs -format-template")
+		}
+		return &templateSink{w: w, tmpl: opts.template}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %s", format)
+}
+
+// textSink writes the default human-readable format, one line per
+// finding, optionally followed by an indented curl command.
+type textSink struct {
+	w    io.Writer
+	curl bool
+}
+
+func (s *textSink) write(r Result) error {
+	param := r.Param
+	if r.Sink != "" {
+		param = fmt.Sprintf("%s (%s: %s)", param, r.Location, r.Sink)
+	} else if r.Location != locQuery {
+		param = fmt.Sprintf("%s (%s)", param, r.Location)
+	}
+	if r.StoredAt != "" {
+		param = fmt.Sprintf("%s [Stored at %s]", param, r.StoredAt)
+	}
+	context := ""
+	if len(r.Context) > 0 {
+		context = fmt.Sprintf(" Context: %v", r.Context)
+	}
+	for _, e := range r.Evasions {
+		context += fmt.Sprintf(" [%s via %s]", e.Char, e.Technique)
+	}
+	for _, e := range r.Decoded {
+		context += fmt.Sprintf(" [%s decoded from %s]", e.Char, e.Technique)
+	}
+	if r.Verified {
+		param += " [Verified XSS]"
+	}
+	for _, label := range findingLabels(r) {
+		param += " [" + label + "]"
+	}
+	_, err := fmt.Fprintf(s.w, "URL: %s Param: %s Unfiltered: %v%s\n", r.URL, param, r.Unfiltered, context)
+	if err == nil && s.curl && r.Curl != "" {
+		_, err = fmt.Fprintf(s.w, "    %s\n", r.Curl)
+	}
+	return err
+}
+
+func (s *textSink) close() error {
+	return nil
+}
+
+// jsonSink writes each finding as a JSON object: pretty-printed for -j,
+// or compact with exactly one object per line for -jsonl.
+type jsonSink struct {
+	w      io.Writer
+	indent bool
+}
+
+func (s *jsonSink) write(r Result) error {
+	var jsonData []byte
+	var err error
+	if s.indent {
+		jsonData, err = json.MarshalIndent(r, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(r)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(s.w, string(jsonData))
+	return err
+}
+
+func (s *jsonSink) close() error {
+	return nil
+}
+
+// delimitedSink writes one bare line per finding, url, param, unfiltered
+// characters and whether a database error was seen, separated by delim,
+// for awk, cut and sort pipelines.
+type delimitedSink struct {
+	w     io.Writer
+	delim string
+}
+
+func (s *delimitedSink) write(r Result) error {
+	_, err := fmt.Fprintln(s.w, strings.Join([]string{
+		r.URL,
+		r.Param,
+		strings.Join(r.Unfiltered, ""),
+		strconv.FormatBool(r.SQLInjection),
+	}, s.delim))
+	return err
+}
+
+func (s *delimitedSink) close() error {
+	return nil
+}
+
+// templateFuncs are available to -format-template in addition to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"join": func(elems []string, sep string) string {
+		return strings.Join(elems, sep)
+	},
+}
+
+// parseFormatTemplate compiles a -format-template. Each finding is
+// rendered on its own line, so a trailing newline is added when the
+// template does not end with one.
+func parseFormatTemplate(text string) (*template.Template, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return template.New("format").Funcs(templateFuncs).Parse(text)
+}
+
+// templateSink renders each finding with a user-supplied text/template.
+type templateSink struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func (s *templateSink) write(r Result) error {
+	return s.tmpl.Execute(s.w, r)
+}
+
+func (s *templateSink) close() error {
+	return nil
+}
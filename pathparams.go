@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// testPath is set by -test-path to also test the segments of URL paths.
+var testPath bool
+
+// splitPath cuts urlStr around its path as written, without re-encoding
+// anything.
+func splitPath(urlStr string) (prefix, path, rest string) {
+	scheme, after, ok := strings.Cut(urlStr, "://")
+	if !ok {
+		return urlStr, "", ""
+	}
+	i := strings.IndexAny(after, "/?#")
+	if i < 0 || after[i] != '/' {
+		return urlStr, "", ""
+	}
+	path = after[i:]
+	if j := strings.IndexAny(path, "?#"); j >= 0 {
+		path, rest = path[:j], path[j:]
+	}
+	return scheme + "://" + after[:i], path, rest
+}
+
+// pathChecks returns a check for every path segment of c's URL that
+// respBody reflects, and for the last segment in any case since that is
+// the one error pages tend to echo. Segments are named by their position,
+// starting at 1.
+func pathChecks(c paramCheck, respBody string) []paramCheck {
+	out := make([]paramCheck, 0)
+	_, path, _ := splitPath(c.url)
+	segments := strings.Split(path, "/")
+	last := len(segments) - 1
+	for last > 0 && segments[last] == "" {
+		last--
+	}
+	for i := 1; i < len(segments); i++ {
+		v, err := url.PathUnescape(segments[i])
+		if err != nil || v == "" {
+			continue
+		}
+		if i == last || strings.Contains(respBody, v) {
+			out = append(out, paramCheck{url: c.url, param: strconv.Itoa(i), loc: locPath, tmpl: c.tmpl})
+		}
+	}
+	return out
+}
+
+// editPathSegment rewrites path segment n of urlStr with edit, which gets
+// and returns the segment in its escaped form. The rest of the URL is left
+// as it was.
+func editPathSegment(urlStr, n string, edit func(string) string) (string, error) {
+	prefix, path, rest := splitPath(urlStr)
+	segments := strings.Split(path, "/")
+	i, err := strconv.Atoi(n)
+	if err != nil || i < 1 || i >= len(segments) {
+		return "", fmt.Errorf("no path segment %s in %s", n, urlStr)
+	}
+	segments[i] = edit(segments[i])
+	return prefix + strings.Join(segments, "/") + rest, nil
+}
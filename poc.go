@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// pocPayload is a harmless marker that shows up as a new <kxss123> tag
+// when the parameter breaks out of an attribute unfiltered.
+const pocPayload = `"><kxss123>`
+
+// pocRequest returns the URL and body of c's request with the tested
+// parameter set to pocPayload. Header checks leave both as they are.
+func pocRequest(c paramCheck) (string, string, error) {
+	return c.withValue(pocPayload)
+}
+
+// pocURL returns c's URL with the tested parameter set to pocPayload, or
+// "" when the parameter is not in the query string or path and so cannot
+// be reproduced with a link.
+func pocURL(c paramCheck) string {
+	if c.loc != locQuery && c.loc != locPath {
+		return ""
+	}
+	u, _, err := pocRequest(c)
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// curlCommand returns a shell command sending the same request kxss would,
+// with the tested parameter set to pocPayload.
+func curlCommand(c paramCheck) string {
+	urlStr, body, err := pocRequest(c)
+	if err != nil {
+		return ""
+	}
+	c.headerValue = pocPayload
+	return curlRequest(c, urlStr, body)
+}
+
+// curlRequest returns a shell command sending c's request with the given
+// URL and body, and c.headerValue in the tested header for header checks.
+func curlRequest(c paramCheck, urlStr, body string) string {
+	method := "GET"
+	var header http.Header
+	if c.tmpl != nil {
+		method = c.tmpl.Method
+		header = c.tmpl.Header
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	header = requestHeader(u, header)
+	if c.loc == locHeader {
+		header.Set(c.param, c.headerValue)
+	}
+
+	args := []string{"curl", "-s", "-i"}
+	args = append(args, curlTransportArgs...)
+	if rawPaths {
+		args = append(args, "--path-as-is", "--globoff")
+	}
+	if method != "GET" && !(method == "POST" && body != "") {
+		args = append(args, "-X", shellQuote(method))
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range header[name] {
+			args = append(args, "-H", shellQuote(name+": "+v))
+		}
+	}
+	if body != "" {
+		args = append(args, "--data-raw", shellQuote(body))
+	}
+	args = append(args, shellQuote(urlStr))
+	return strings.Join(args, " ")
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// postmanCollection covers the parts of a Postman v2.0/v2.1 collection
+// needed to rebuild its requests.
+type postmanCollection struct {
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+type postmanVariable struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// postmanItem is either a request or a folder of further items.
+type postmanItem struct {
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanVariable `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanVariable `json:"urlencoded"`
+	} `json:"body"`
+}
+
+// postmanURL is written either as a plain string or as an object with a
+// raw field, depending on the exporting Postman version.
+type postmanURL struct {
+	Raw string
+}
+
+func (u *postmanURL) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &u.Raw); err == nil {
+		return nil
+	}
+	var obj struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	u.Raw = obj.Raw
+	return nil
+}
+
+var postmanVarPattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// readPostmanChecks returns a check for every request in a collection,
+// with collection variables substituted and headers carried over.
+// Requests whose URL still holds unknown variables are skipped.
+func readPostmanChecks(path string) ([]paramCheck, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var coll postmanCollection
+	if err := json.Unmarshal(raw, &coll); err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	for _, v := range coll.Variable {
+		if !v.Disabled {
+			vars[v.Key] = v.Value
+		}
+	}
+	expand := func(s string) string {
+		return postmanVarPattern.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := vars[strings.TrimSpace(m[2:len(m)-2])]; ok {
+				return v
+			}
+			return m
+		})
+	}
+
+	out := make([]paramCheck, 0)
+	var walk func(items []postmanItem)
+	walk = func(items []postmanItem) {
+		for _, item := range items {
+			walk(item.Item)
+			if item.Request == nil {
+				continue
+			}
+			if c, ok := postmanCheck(item.Request, expand); ok {
+				out = append(out, c)
+			}
+		}
+	}
+	walk(coll.Item)
+	return out, nil
+}
+
+func postmanCheck(r *postmanRequest, expand func(string) string) (paramCheck, bool) {
+	rawURL := expand(r.URL.Raw)
+	if postmanVarPattern.MatchString(rawURL) {
+		return paramCheck{}, false
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	if _, err := url.Parse(rawURL); err != nil {
+		return paramCheck{}, false
+	}
+
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = "GET"
+	}
+	header := make(http.Header)
+	for _, h := range r.Header {
+		if !h.Disabled && !templateHeaderSkip[http.CanonicalHeaderKey(h.Key)] {
+			header.Add(h.Key, expand(h.Value))
+		}
+	}
+
+	body := ""
+	if r.Body != nil {
+		switch r.Body.Mode {
+		case "urlencoded":
+			form := url.Values{}
+			for _, f := range r.Body.URLEncoded {
+				if !f.Disabled {
+					form.Add(f.Key, expand(f.Value))
+				}
+			}
+			body = form.Encode()
+			header.Set("Content-Type", "application/x-www-form-urlencoded")
+		case "raw":
+			body = expand(r.Body.Raw)
+		}
+	}
+
+	return paramCheck{url: rawURL, tmpl: &requestTemplate{
+		Method: method,
+		Header: header,
+		Body:   body,
+	}}, true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// proxyRotator spreads scan requests over several upstream proxies, either
+// taking the next one for every request or pinning each target host to
+// one proxy so its session and rate limits stay on a single address.
+type proxyRotator struct {
+	proxies []*url.URL
+	sticky  bool
+
+	mu     sync.Mutex
+	next   int
+	byHost map[string]*url.URL
+}
+
+// setProxyList routes scan requests through the proxies listed in path,
+// one URL per line. mode is "round-robin" or "sticky".
+func setProxyList(path, mode string) error {
+	r := &proxyRotator{byHost: make(map[string]*url.URL)}
+	switch mode {
+	case "round-robin":
+	case "sticky":
+		r.sticky = true
+	default:
+		return fmt.Errorf("-proxy-rotate must be round-robin or sticky")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := parseProxyURL(line)
+		if err != nil {
+			return fmt.Errorf("%s: %v", line, err)
+		}
+		r.proxies = append(r.proxies, u)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(r.proxies) == 0 {
+		return fmt.Errorf("no proxies found")
+	}
+	transport.Proxy = r.proxy
+	return nil
+}
+
+// proxy has the signature of http.Transport.Proxy.
+func (r *proxyRotator) proxy(req *http.Request) (*url.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sticky {
+		if u, ok := r.byHost[req.URL.Host]; ok {
+			return u, nil
+		}
+	}
+	u := r.proxies[r.next]
+	r.next = (r.next + 1) % len(r.proxies)
+	if r.sticky {
+		r.byHost[req.URL.Host] = u
+	}
+	return u, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// tokenBucket spaces out requests to at most rate per second. It starts
+// with a single token so requests are evenly paced rather than bursty.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perSecond float64) *tokenBucket {
+	return &tokenBucket{rate: perSecond, tokens: 1, last: time.Now()}
+}
+
+// wait blocks until a token is available and takes it. A nil bucket never
+// blocks.
+func (b *tokenBucket) wait() {
+	if b == nil {
+		return
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > 1 {
+			b.tokens = 1
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		time.Sleep(delay)
+	}
+}
+
+// rateLimit is the -rate bucket shared by all workers; nil means no limit.
+var rateLimit *tokenBucket
+
+// hostLimiter keeps a separate token bucket per host, so no single origin
+// sees more than rate requests per second however many workers run.
+type hostLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	buckets map[string]*tokenBucket
+}
+
+func newHostLimiter(perSecond float64) *hostLimiter {
+	return &hostLimiter{rate: perSecond, buckets: make(map[string]*tokenBucket)}
+}
+
+// wait blocks until host may be sent another request. A nil limiter never
+// blocks.
+func (l *hostLimiter) wait(host string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = newTokenBucket(l.rate)
+		l.buckets[host] = b
+	}
+	l.mu.Unlock()
+	b.wait()
+}
+
+// hostRateLimit is the -rate-per-host limiter; nil means no limit.
+var hostRateLimit *hostLimiter
+
+// requestDelay and requestJitter are set by -delay and -jitter. Every
+// request waits requestDelay plus a random part of requestJitter, which
+// spaces out the requests of each worker.
+var (
+	requestDelay  time.Duration
+	requestJitter time.Duration
+)
+
+func pause() {
+	d := requestDelay
+	if requestJitter > 0 {
+		d += rand.N(requestJitter)
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
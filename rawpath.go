@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// rawPaths is set by -raw-path: request paths and queries go out exactly
+// as written in the input, and probes only touch the tested value.
+var rawPaths bool
+
+// setRawRequestURI makes req send the path of urlStr byte for byte rather
+// than net/url's re-escaped form. The query is already kept as written.
+func setRawRequestURI(req *http.Request, urlStr string) {
+	_, rest, ok := strings.Cut(urlStr, "://")
+	if !ok {
+		return
+	}
+	i := strings.IndexAny(rest, "/?#")
+	if i < 0 || rest[i] != '/' {
+		return
+	}
+	path := rest[i:]
+	if j := strings.IndexAny(path, "?#"); j >= 0 {
+		path = path[:j]
+	}
+	// A leading "//" would make the Opaque form scheme-relative
+	if strings.HasPrefix(path, "//") {
+		return
+	}
+	req.URL.Opaque = path
+}
+
+// editRawQuery rewrites the first value of param in rawQuery with edit,
+// which gets and returns the value in its escaped form. Every other byte
+// of the query is left as it was; false means param is not present.
+func editRawQuery(rawQuery, param string, edit func(string) string) (string, bool) {
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err != nil || k != param {
+			continue
+		}
+		pairs[i] = key + "=" + edit(value)
+		return strings.Join(pairs, "&"), true
+	}
+	return rawQuery, false
+}
+
+// replaceRawQuery swaps the query of urlStr for rawQuery without
+// re-encoding the rest of the URL.
+func replaceRawQuery(urlStr, rawQuery string) string {
+	base, _, _ := strings.Cut(urlStr, "#")
+	base, _, _ = strings.Cut(base, "?")
+	return base + "?" + rawQuery
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// rawSocket is set by -raw-socket: requests are written to the connection
+// by kxss itself so header names keep the casing and order they were
+// given in, instead of going through net/http's canonical map.
+var rawSocket bool
+
+// headerOrder lists header names as written in the -r request and with
+// -H, in the order the raw writer sends them.
+var headerOrder []string
+
+// sendRequest sends req with the raw writer under -raw-socket, or the
+// shared client otherwise.
+func sendRequest(req *http.Request) (*http.Response, error) {
+	if !rawSocket {
+		return httpClient.Do(req)
+	}
+	return rawRoundTrip(req)
+}
+
+// rawRoundTrip sends req as HTTP/1.1 over a fresh connection. It honours
+// the dialer, TLS and timeout settings of the shared client and its cookie
+// jar, but not proxies or HTTP/2.
+func rawRoundTrip(req *http.Request) (*http.Response, error) {
+	if jar := httpClient.Jar; jar != nil {
+		for _, c := range jar.Cookies(req.URL) {
+			req.AddCookie(c)
+		}
+	}
+
+	ctx := context.Background()
+	if httpClient.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, httpClient.Timeout)
+		defer cancel()
+	}
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+	conn, err := transport.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == "https" {
+		cfg := transport.TLSClientConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = req.URL.Hostname()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := writeRawRequest(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body = &connBody{resp.Body, conn}
+	if jar := httpClient.Jar; jar != nil {
+		jar.SetCookies(req.URL, resp.Cookies())
+	}
+	return resp, nil
+}
+
+// writeRawRequest writes req with the headers named in headerOrder first,
+// spelled as given there, followed by the rest in sorted order.
+func writeRawRequest(w io.Writer, req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header := req.Header.Clone()
+	header.Set("Host", host)
+	header.Del("Content-Length")
+	header.Del("Connection")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	writeHeader := func(name string, values []string) {
+		for _, v := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", name, v)
+		}
+	}
+	for _, name := range headerOrder {
+		key := http.CanonicalHeaderKey(name)
+		if vv, ok := header[key]; ok {
+			writeHeader(name, vv)
+			delete(header, key)
+		}
+	}
+	if vv, ok := header["Host"]; ok {
+		writeHeader("Host", vv)
+		delete(header, "Host")
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(name, header[name])
+	}
+	if len(body) > 0 || req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH" {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	}
+	b.WriteString("Connection: close\r\n\r\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// connBody closes the connection a raw response was read from together
+// with its body.
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"html/template"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hostSummary aggregates the findings for one host.
+type hostSummary struct {
+	Host         string
+	Findings     int
+	Params       int
+	SQLInjection int
+}
+
+// charCount is how many findings left one special character unfiltered.
+type charCount struct {
+	Char  string
+	Count int
+}
+
+// summarizeHosts groups results by URL host, busiest host first.
+func summarizeHosts(results []Result) []hostSummary {
+	byHost := make(map[string]*hostSummary)
+	params := make(map[string]map[string]bool)
+	for _, r := range results {
+		host := resultHost(r)
+		h, ok := byHost[host]
+		if !ok {
+			h = &hostSummary{Host: host}
+			byHost[host] = h
+			params[host] = make(map[string]bool)
+		}
+		h.Findings++
+		if r.SQLInjection {
+			h.SQLInjection++
+		}
+		params[host][r.Param] = true
+	}
+
+	out := make([]hostSummary, 0, len(byHost))
+	for host, h := range byHost {
+		h.Params = len(params[host])
+		out = append(out, *h)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Findings != out[j].Findings {
+			return out[i].Findings > out[j].Findings
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// countChars tallies unfiltered characters across results, in the order
+// they are probed.
+func countChars(results []Result) []charCount {
+	counts := make(map[string]int)
+	for _, r := range results {
+		for _, c := range r.Unfiltered {
+			counts[c]++
+		}
+	}
+	out := make([]charCount, 0, len(counts))
+	for _, c := range specialChars {
+		if counts[c] > 0 {
+			out = append(out, charCount{c, counts[c]})
+		}
+	}
+	return out
+}
+
+// kindCount is how many findings were of one kind, as named by the labels
+// of findingLabels without their detail.
+type kindCount struct {
+	Kind  string
+	Count int
+}
+
+// countKinds tallies the kinds of finding across results, most common
+// first.
+func countKinds(results []Result) []kindCount {
+	counts := make(map[string]int)
+	for _, r := range results {
+		for _, label := range findingLabels(r) {
+			kind, _, _ := strings.Cut(label, ": ")
+			counts[kind]++
+		}
+	}
+	out := make([]kindCount, 0, len(counts))
+	for kind, n := range counts {
+		out = append(out, kindCount{kind, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
+
+func resultHost(r Result) string {
+	u, err := url.Parse(r.URL)
+	if err != nil || u.Host == "" {
+		return r.URL
+	}
+	return u.Host
+}
+
+// htmlReportSink collects findings and writes a self-contained HTML
+// report to path on close.
+type htmlReportSink struct {
+	path    string
+	results []Result
+}
+
+func (s *htmlReportSink) write(r Result) error {
+	s.results = append(s.results, r)
+	return nil
+}
+
+func (s *htmlReportSink) close() error {
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return htmlReportTemplate.Execute(file, map[string]any{
+		"Generated": time.Now().UTC().Format(time.RFC3339),
+		"Results":   s.results,
+		"Hosts":     summarizeHosts(s.results),
+		"Chars":     countChars(s.results),
+		"Kinds":     countKinds(s.results),
+	})
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"labels": findingLabels,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>kxss report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+td.url { word-break: break-all; }
+code { background: #f6f6f6; padding: 0 3px; }
+.sqli, .sev-high { color: #b00; font-weight: bold; }
+.sev-medium { color: #b60; }
+</style>
+</head>
+<body>
+<h1>kxss report</h1>
+<p>Generated {{.Generated}} &middot; {{len .Results}} findings on {{len .Hosts}} hosts</p>
+
+<h2>Hosts</h2>
+<table class="sortable">
+<thead><tr><th>Host</th><th>Findings</th><th>Parameters</th><th>SQL errors</th></tr></thead>
+<tbody>
+{{range .Hosts}}<tr><td>{{.Host}}</td><td>{{.Findings}}</td><td>{{.Params}}</td><td>{{.SQLInjection}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Unfiltered characters</h2>
+<table class="sortable">
+<thead><tr><th>Character</th><th>Findings</th></tr></thead>
+<tbody>
+{{range .Chars}}<tr><td><code>{{.Char}}</code></td><td>{{.Count}}</td></tr>
+{{end}}</tbody>
+</table>
+{{if .Kinds}}
+<h2>Injection findings</h2>
+<table class="sortable">
+<thead><tr><th>Kind</th><th>Findings</th></tr></thead>
+<tbody>
+{{range .Kinds}}<tr><td>{{.Kind}}</td><td>{{.Count}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+<h2>Findings</h2>
+<table class="sortable">
+<thead><tr><th>URL</th><th>Parameter</th><th>Location</th><th>Severity</th><th>Unfiltered</th><th>Kind</th><th>PoC</th></tr></thead>
+<tbody>
+{{range .Results}}<tr><td class="url"><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Param}}</td><td>{{.Location}}</td><td class="sev-{{.Severity}}">{{.Severity}}</td><td>{{range .Unfiltered}}<code>{{.}}</code> {{end}}</td><td>{{range labels .}}<span class="sqli">{{.}}</span><br>{{end}}</td><td>{{with .PoC}}<a href="{{.}}" rel="noreferrer">open</a>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table"), body = table.tBodies[0];
+    var col = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var asc = th.dataset.dir !== "asc";
+    th.parentNode.querySelectorAll("th").forEach(function (h) { delete h.dataset.dir; });
+    th.dataset.dir = asc ? "asc" : "desc";
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[col].textContent.trim(), y = b.cells[col].textContent.trim();
+      var n = parseFloat(x) - parseFloat(y);
+      var cmp = isNaN(n) ? x.localeCompare(y) : n;
+      return asc ? cmp : -cmp;
+    });
+    rows.forEach(function (r) { body.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// requestTemplate carries everything about a target request beyond its
+// URL, so that probes can be sent with the same method, headers and body
+// as the original.
+type requestTemplate struct {
+	Method string
+	Header http.Header
+	Body   string
+	Order  []string // header names as written in a raw request, in order
+}
+
+// hasFormBody reports whether the template body is form-urlencoded and
+// can therefore have its parameters mutated.
+func (t *requestTemplate) hasFormBody() bool {
+	if t == nil || t.Body == "" {
+		return false
+	}
+	return strings.Contains(t.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
+
+// extraHeaders holds -H headers, sent with every request and taking
+// precedence over template headers of the same name.
+var extraHeaders = make(http.Header)
+
+// parseHeaderLine splits a "Name: value" header given on the command line.
+func parseHeaderLine(h string) (string, string, error) {
+	name, value, ok := strings.Cut(h, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("header %q must look like Name: value", h)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// requestHeader returns the headers to send to u for a request whose
+// template (if any) carries tmpl: the template headers, then -H headers,
+// then those of matching -host-rules, then those from -login-script,
+// then a User-Agent. -random-agent replaces the template's User-Agent but
+// not one given explicitly.
+func requestHeader(u *url.URL, tmpl http.Header) http.Header {
+	header := make(http.Header, len(tmpl)+len(extraHeaders)+1)
+	for k, vv := range tmpl {
+		header[k] = vv
+	}
+	for k, vv := range extraHeaders {
+		header[k] = vv
+	}
+	if randomAgent && extraHeaders.Get("User-Agent") == "" {
+		header.Set("User-Agent", randomUserAgent())
+	}
+	hostRules.apply(u, header)
+	session.apply(header)
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", defaultUserAgent)
+	}
+	return header
+}
+
+// templateHeaderSkip lists headers from a raw request that must not be
+// replayed verbatim because net/http manages them itself.
+var templateHeaderSkip = map[string]bool{
+	"Content-Length":    true,
+	"Connection":        true,
+	"Accept-Encoding":   true,
+	"Transfer-Encoding": true,
+}
+
+// readRawRequest parses a raw HTTP request file (as saved by Burp or used
+// with sqlmap -r) and returns the target URL and a template holding its
+// method, headers and body. scheme is used when the request line carries
+// only a path.
+func readRawRequest(path, scheme string) (string, *requestTemplate, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+
+	head, body, _ := bytes.Cut(raw, []byte("\n\n"))
+	head = bytes.ReplaceAll(append(head, "\n\n"...), []byte("\n"), []byte("\r\n"))
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(head)))
+	if err != nil {
+		return "", nil, err
+	}
+
+	u := req.URL
+	if u.Host == "" {
+		if req.Host == "" {
+			return "", nil, fmt.Errorf("no Host header in request")
+		}
+		u = &url.URL{
+			Scheme:   scheme,
+			Host:     req.Host,
+			Path:     req.URL.Path,
+			RawPath:  req.URL.RawPath,
+			RawQuery: req.URL.RawQuery,
+		}
+	}
+
+	header := make(http.Header)
+	for k, vv := range req.Header {
+		if templateHeaderSkip[k] {
+			continue
+		}
+		header[k] = vv
+	}
+	var order []string
+	lines := strings.Split(string(head), "\r\n")
+	for _, line := range lines[1:] {
+		if name, _, ok := strings.Cut(line, ":"); ok {
+			order = append(order, strings.TrimSpace(name))
+		}
+	}
+
+	tmpl := &requestTemplate{
+		Method: req.Method,
+		Header: header,
+		Body:   strings.TrimRight(string(body), "\n"),
+		Order:  order,
+	}
+	// Line endings were normalised above, but multipart bodies need CRLF
+	if multipartBoundary(header.Get("Content-Type")) != "" {
+		tmpl.Body = strings.ReplaceAll(tmpl.Body, "\n", "\r\n")
+	}
+	return u.String(), tmpl, nil
+}
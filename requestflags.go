@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// requestFlags are the options that shape how requests are sent: headers,
+// authentication, TLS, proxies and pacing. The scan and "kxss verify"
+// register the same set, so that findings behind a login or a proxy can
+// be tested again the way they were found.
+type requestFlags struct {
+	scheme         string
+	proxy          string
+	proxyList      string
+	proxyRotate    string
+	timeout        time.Duration
+	httpVersion    string
+	certFile       string
+	keyFile        string
+	insecure       bool
+	resolvers      stringList
+	dohURL         string
+	digestCreds    string
+	hostHeader     string
+	useCSRF        bool
+	csrfFields     stringList
+	csrfRegex      string
+	csrfURL        string
+	loginFile      string
+	loginScript    string
+	logoutPattern  string
+	hostRulesFile  string
+	sourceIP       string
+	unixSocket     string
+	sni            string
+	tlsFingerprint string
+	iface          string
+	caFile         string
+	rate           float64
+	ratePerHost    float64
+	cookieJarFile  string
+}
+
+// register defines the request flags on fs.
+func (o *requestFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.scheme, "scheme", "https", "scheme to use for the -r and -login requests and bare -hosts")
+	fs.Func("H", "header to send with every request, e.g. \"Authorization: Bearer TOKEN\" (repeatable)", func(s string) error {
+		name, value, err := parseHeaderLine(s)
+		if err != nil {
+			return err
+		}
+		headerOrder = append(headerOrder, name)
+		if http.CanonicalHeaderKey(name) == "Cookie" {
+			addCookieHeader(value)
+			return nil
+		}
+		extraHeaders.Add(name, value)
+		return nil
+	})
+	fs.Func("cookie", "cookies to send with every request, e.g. \"sid=abc; lang=en\" (repeatable)", func(s string) error {
+		addCookieHeader(s)
+		return nil
+	})
+	fs.StringVar(&o.cookieJarFile, "cookie-jar", "", "Netscape cookies.txt file to load into a cookie jar that follows Set-Cookie updates")
+	fs.Func("user-agent", "User-Agent to send instead of the default Chrome one", func(s string) error {
+		extraHeaders.Set("User-Agent", s)
+		return nil
+	})
+	fs.BoolVar(&randomAgent, "random-agent", false, "send a random browser User-Agent with each request")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "time limit for each request, including reading the response (0 for none)")
+	fs.Float64Var(&o.rate, "rate", 0, "maximum requests per second across all workers (0 for no limit)")
+	fs.Float64Var(&o.ratePerHost, "rate-per-host", 0, "maximum requests per second to any single host (0 for no limit)")
+	fs.StringVar(&o.httpVersion, "http-version", "1.1", "protocol to use: 1.1, 2 (negotiate HTTP/2 over TLS) or 2-only (force h2, h2c for http://)")
+	fs.BoolVar(&o.insecure, "insecure", false, "skip TLS certificate verification")
+	fs.StringVar(&o.caFile, "ca", "", "PEM bundle of extra CA certificates to trust, e.g. an internal CA")
+	fs.StringVar(&o.certFile, "cert", "", "PEM client certificate for mutual TLS")
+	fs.StringVar(&o.keyFile, "key", "", "PEM private key for -cert (default: read from the -cert file)")
+	fs.Var(&o.resolvers, "resolvers", "DNS servers to resolve targets with instead of the system resolver, e.g. 1.1.1.1,8.8.8.8")
+	fs.StringVar(&o.dohURL, "doh", "", "DNS over HTTPS endpoint to resolve targets with, e.g. https://cloudflare-dns.com/dns-query")
+	fs.DurationVar(&requestDelay, "delay", 0, "pause before each request a worker sends, e.g. 200ms")
+	fs.DurationVar(&requestJitter, "jitter", 0, "add a random pause of up to this long to -delay")
+	fs.StringVar(&o.digestCreds, "digest-auth", "", "user:password to answer HTTP Digest authentication challenges with")
+	fs.StringVar(&o.sourceIP, "source-ip", "", "local address to send requests from on a multi-homed host")
+	fs.StringVar(&o.iface, "interface", "", "network interface whose address requests are sent from, e.g. eth1")
+	fs.StringVar(&o.sni, "sni", "", "TLS server name to send instead of the URL host, e.g. when scanning an origin IP")
+	fs.StringVar(&o.tlsFingerprint, "tls-fingerprint", "", "send the TLS ClientHello of a browser: chrome, firefox, safari, edge or ios, offering only http/1.1 in ALPN")
+	fs.StringVar(&o.unixSocket, "unix-socket", "", "Unix domain socket to send every request to, e.g. /var/run/app.sock")
+	fs.StringVar(&o.hostRulesFile, "host-rules", "", "YAML file of headers and cookies to add to requests for matching hosts")
+	fs.StringVar(&o.loginFile, "login", "", "raw HTTP login request to send at the start and whenever the session expires")
+	fs.StringVar(&o.loginScript, "login-script", "", "command printing \"Name: value\" session headers, run at the start and whenever the session expires")
+	fs.StringVar(&o.logoutPattern, "logout-pattern", defaultLogoutPattern, "regexp matching the Location of redirects that mean the session expired")
+	fs.BoolVar(&o.useCSRF, "csrf", false, "fetch a fresh anti-CSRF token into form bodies before each request")
+	fs.Var(&o.csrfFields, "csrf-fields", "names of anti-CSRF fields and headers for -csrf (default: common framework names)")
+	fs.StringVar(&o.csrfRegex, "csrf-regex", "", "regexp whose first group extracts the -csrf token, instead of reading hidden inputs and meta tags")
+	fs.StringVar(&o.csrfURL, "csrf-url", "", "page to read the -csrf token from (default: the target URL)")
+	fs.StringVar(&o.hostHeader, "host-header", "", "Host header to send instead of the URL's host, e.g. to test an origin IP as a virtual host; also the TLS server name unless -sni is given")
+	fs.BoolVar(&rawSocket, "raw-socket", false, "write requests to the socket directly, keeping header casing and order from -r and -H (HTTP/1.1, no proxy)")
+	fs.BoolVar(&rawPaths, "raw-path", false, "send paths and queries exactly as given, without re-encoding them or the untested parameters")
+	fs.StringVar(&o.proxy, "proxy", "", "proxy for all scan requests: http://, https:// or socks5://host:port")
+	fs.StringVar(&o.proxyList, "proxy-list", "", "file of proxy URLs, one per line, to spread scan requests over")
+	fs.StringVar(&o.proxyRotate, "proxy-rotate", "round-robin", "how -proxy-list proxies are picked: round-robin (per request) or sticky (per host)")
+}
+
+// apply sets up the shared client and request state from the flags and
+// logs in with -login or -login-script, exiting on any error.
+func (o *requestFlags) apply() {
+	httpClient.Timeout = o.timeout
+	if o.insecure {
+		setInsecure()
+	}
+	if o.sourceIP != "" && o.iface != "" {
+		fmt.Fprintf(os.Stderr, "-source-ip and -interface cannot be used together\n")
+		os.Exit(1)
+	}
+	if o.sourceIP != "" {
+		if err := setSourceIP(o.sourceIP); err != nil {
+			fmt.Fprintf(os.Stderr, "error in -source-ip: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if o.iface != "" {
+		if err := setInterface(o.iface); err != nil {
+			fmt.Fprintf(os.Stderr, "error in -interface: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if o.sni != "" {
+		setSNI(o.sni)
+	}
+	if o.tlsFingerprint != "" {
+		switch {
+		case setTLSFingerprint == nil:
+			fmt.Fprintf(os.Stderr, "-tls-fingerprint is not available in this build (built with -tags noutls)\n")
+			os.Exit(1)
+		case o.httpVersion != "1.1":
+			fmt.Fprintf(os.Stderr, "-tls-fingerprint cannot be used with -http-version\n")
+			os.Exit(1)
+		}
+		if err := setTLSFingerprint(o.tlsFingerprint); err != nil {
+			fmt.Fprintf(os.Stderr, "error in -tls-fingerprint: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if o.unixSocket != "" {
+		setUnixSocket(o.unixSocket)
+	}
+	if o.hostRulesFile != "" {
+		rules, err := loadHostRules(o.hostRulesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading host rules %s: %s\n", o.hostRulesFile, err)
+			os.Exit(1)
+		}
+		hostRules = rules
+	}
+	if o.useCSRF {
+		r, err := newCSRFRefresher(o.csrfFields, o.csrfRegex, o.csrfURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error in -csrf-regex: %s\n", err)
+			os.Exit(1)
+		}
+		csrf = r
+	}
+	if o.hostHeader != "" {
+		extraHeaders.Set("Host", o.hostHeader)
+		// The certificate is for the virtual host, not the address dialled
+		if o.sni == "" {
+			name := o.hostHeader
+			if h, _, err := net.SplitHostPort(o.hostHeader); err == nil {
+				name = h
+			}
+			setSNI(name)
+		}
+	}
+	if o.digestCreds != "" {
+		d, err := newDigestAuth(o.digestCreds)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error in -digest-auth: %s\n", err)
+			os.Exit(1)
+		}
+		digest = d
+	}
+	switch {
+	case len(o.resolvers) > 0 && o.dohURL != "":
+		fmt.Fprintf(os.Stderr, "-resolvers and -doh cannot be used together\n")
+		os.Exit(1)
+	case len(o.resolvers) > 0:
+		if err := setResolvers(o.resolvers); err != nil {
+			fmt.Fprintf(os.Stderr, "error setting resolvers: %s\n", err)
+			os.Exit(1)
+		}
+	case o.dohURL != "":
+		if err := setDoH(o.dohURL); err != nil {
+			fmt.Fprintf(os.Stderr, "error setting DoH endpoint: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if o.caFile != "" {
+		if err := setCABundle(o.caFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error loading CA bundle %s: %s\n", o.caFile, err)
+			os.Exit(1)
+		}
+	}
+	if o.certFile != "" {
+		if err := setClientCert(o.certFile, o.keyFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error loading client certificate %s: %s\n", o.certFile, err)
+			os.Exit(1)
+		}
+	}
+	if err := setHTTPVersion(o.httpVersion); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	if o.rate > 0 {
+		rateLimit = newTokenBucket(o.rate)
+	}
+	if o.ratePerHost > 0 {
+		hostRateLimit = newHostLimiter(o.ratePerHost)
+	}
+
+	if o.cookieJarFile != "" {
+		jar, err := loadCookieJar(o.cookieJarFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading cookie jar %s: %s\n", o.cookieJarFile, err)
+			os.Exit(1)
+		}
+		httpClient.Jar = jar
+	}
+
+	if rawSocket && (o.proxy != "" || o.proxyList != "" || o.httpVersion != "1.1") {
+		fmt.Fprintf(os.Stderr, "-raw-socket cannot be used with -proxy, -proxy-list or -http-version\n")
+		os.Exit(1)
+	}
+	// Tunnelled HTTPS bypasses DialTLSContext, and with it the uTLS hello
+	if o.tlsFingerprint != "" && (o.proxy != "" || o.proxyList != "") {
+		fmt.Fprintf(os.Stderr, "-tls-fingerprint cannot be used with -proxy or -proxy-list\n")
+		os.Exit(1)
+	}
+	if o.proxy != "" && o.proxyList != "" {
+		fmt.Fprintf(os.Stderr, "-proxy and -proxy-list cannot be used together\n")
+		os.Exit(1)
+	}
+	if o.proxy != "" {
+		if err := setProxy(o.proxy); err != nil {
+			fmt.Fprintf(os.Stderr, "error setting proxy %s: %s\n", o.proxy, err)
+			os.Exit(1)
+		}
+	}
+	if o.proxyList != "" {
+		if err := setProxyList(o.proxyList, o.proxyRotate); err != nil {
+			fmt.Fprintf(os.Stderr, "error loading proxy list %s: %s\n", o.proxyList, err)
+			os.Exit(1)
+		}
+	}
+
+	if o.loginFile != "" && o.loginScript != "" {
+		fmt.Fprintf(os.Stderr, "-login and -login-script cannot be used together\n")
+		os.Exit(1)
+	}
+	if o.loginFile != "" || o.loginScript != "" {
+		s, err := newSessionRefresher(o.loginFile, o.loginScript, o.scheme, o.logoutPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error setting up login: %s\n", err)
+			os.Exit(1)
+		}
+		if _, err := s.refresh(0); err != nil {
+			fmt.Fprintf(os.Stderr, "error logging in: %s\n", err)
+			os.Exit(1)
+		}
+		session = s
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// setResolvers makes the shared transport resolve hostnames through the
+// given DNS servers (host or host:port), rotating between them.
+func setResolvers(servers []string) error {
+	addrs := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			s = net.JoinHostPort(s, "53")
+		}
+		addrs = append(addrs, s)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no resolvers given")
+	}
+
+	var next atomic.Uint32
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	dialer.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			addr := addrs[int(next.Add(1))%len(addrs)]
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	return nil
+}
+
+// setDoH makes the shared transport resolve hostnames with DNS over HTTPS
+// (RFC 8484) against endpoint, e.g. https://cloudflare-dns.com/dns-query.
+func setDoH(endpoint string) error {
+	if !strings.HasPrefix(endpoint, "https://") {
+		return fmt.Errorf("DoH endpoint must be an https:// URL")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	dialer.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, endpoint: endpoint, client: client}, nil
+		},
+	}
+	return nil
+}
+
+// dohConn carries the Go resolver's DNS exchanges over HTTPS. It is not a
+// net.PacketConn, so the resolver uses TCP framing: every message is
+// prefixed with its two-byte length.
+type dohConn struct {
+	ctx      context.Context
+	endpoint string
+	client   *http.Client
+	query    bytes.Buffer
+	resp     bytes.Reader
+	deadline time.Time
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.resp.Len() == 0 {
+		if err := c.exchange(); err != nil {
+			return 0, err
+		}
+	}
+	return c.resp.Read(b)
+}
+
+// exchange posts the buffered query and queues the length-prefixed answer.
+func (c *dohConn) exchange() error {
+	raw := c.query.Bytes()
+	if len(raw) < 2 || int(binary.BigEndian.Uint16(raw)) != len(raw)-2 {
+		return fmt.Errorf("incomplete DNS query")
+	}
+	msg := raw[2:]
+	c.query.Reset()
+
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DoH server returned %s", resp.Status)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return err
+	}
+	framed := make([]byte, 2+len(answer))
+	binary.BigEndian.PutUint16(framed, uint16(len(answer)))
+	copy(framed[2:], answer)
+	c.resp.Reset(framed)
+	return nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr{} }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { c.deadline = t; return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "doh" }
+func (dohAddr) String() string  { return "doh" }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is how often scan progress is persisted.
+const checkpointInterval = 10 * time.Second
+
+// checkpointState is the on-disk form of a checkpoint.
+type checkpointState struct {
+	// Requests whose every reflected parameter has been fully tested, see
+	// checkpointRequest
+	Done []string `json:"done"`
+	// Parameters already tested for requests that are not yet done
+	Params map[string][]string `json:"params"`
+}
+
+// checkpoint tracks which input requests and parameters have been processed
+// so an interrupted scan can be resumed. A nil *checkpoint records
+// nothing.
+type checkpoint struct {
+	saveMu  sync.Mutex
+	mu      sync.Mutex
+	path    string
+	done    map[string]bool
+	params  map[string]map[string]bool
+	pending map[string]int
+	dirty   bool
+}
+
+// loadCheckpoint reads the state at path, if any. The second return value
+// reports whether previous state was found.
+func loadCheckpoint(path string) (*checkpoint, bool, error) {
+	cp := &checkpoint{
+		path:    path,
+		done:    make(map[string]bool),
+		params:  make(map[string]map[string]bool),
+		pending: make(map[string]int),
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, false, err
+	}
+	for _, u := range state.Done {
+		cp.done[u] = true
+	}
+	for u, ps := range state.Params {
+		cp.params[u] = make(map[string]bool)
+		for _, p := range ps {
+			cp.params[u][p] = true
+		}
+	}
+	return cp, true, nil
+}
+
+func checkpointKey(c paramCheck) string {
+	return string(c.loc) + ":" + c.param
+}
+
+// checkpointRequest identifies the request c belongs to: its URL, after
+// the method unless that is GET, so that a GET and a POST to one URL are
+// tracked apart.
+func checkpointRequest(c paramCheck) string {
+	if c.tmpl == nil || c.tmpl.Method == "" || c.tmpl.Method == "GET" {
+		return c.url
+	}
+	return c.tmpl.Method + " " + c.url
+}
+
+// skip reports whether every parameter of c's request was already tested.
+func (cp *checkpoint) skip(c paramCheck) bool {
+	if cp == nil {
+		return false
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.done[checkpointRequest(c)]
+}
+
+// start filters out parameters of the request c that were already tested
+// and records the remaining ones as outstanding. The same request may
+// arrive more than once; it is only done when nothing is outstanding for
+// any of its arrivals.
+func (cp *checkpoint) start(c paramCheck, checks []paramCheck) []paramCheck {
+	if cp == nil {
+		return checks
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	u := checkpointRequest(c)
+	out := make([]paramCheck, 0, len(checks))
+	for _, c := range checks {
+		if cp.params[u][checkpointKey(c)] {
+			continue
+		}
+		out = append(out, c)
+	}
+	cp.pending[u] += len(out)
+	if cp.pending[u] == 0 {
+		cp.markDone(u)
+	} else if cp.done[u] {
+		// an earlier arrival had nothing left to test, but this one has
+		delete(cp.done, u)
+		cp.dirty = true
+	}
+	return out
+}
+
+// finish records that testing of c has completed.
+func (cp *checkpoint) finish(c paramCheck) {
+	if cp == nil {
+		return
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	u := checkpointRequest(c)
+	if cp.params[u] == nil {
+		cp.params[u] = make(map[string]bool)
+	}
+	cp.params[u][checkpointKey(c)] = true
+	cp.dirty = true
+
+	cp.pending[u]--
+	if cp.pending[u] <= 0 {
+		cp.markDone(u)
+	}
+}
+
+// markDone must be called with cp.mu held.
+func (cp *checkpoint) markDone(u string) {
+	cp.done[u] = true
+	delete(cp.params, u)
+	delete(cp.pending, u)
+	cp.dirty = true
+}
+
+// save writes the checkpoint to disk if it changed since the last save.
+func (cp *checkpoint) save() error {
+	if cp == nil {
+		return nil
+	}
+	cp.saveMu.Lock()
+	defer cp.saveMu.Unlock()
+
+	cp.mu.Lock()
+	if !cp.dirty {
+		cp.mu.Unlock()
+		return nil
+	}
+	state := checkpointState{
+		Done:   make([]string, 0, len(cp.done)),
+		Params: make(map[string][]string, len(cp.params)),
+	}
+	for u := range cp.done {
+		state.Done = append(state.Done, u)
+	}
+	for u, ps := range cp.params {
+		for p := range ps {
+			state.Params[u] = append(state.Params[u], p)
+		}
+	}
+	cp.dirty = false
+	cp.mu.Unlock()
+
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves a torn state file
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.path)
+}
+
+// saveEvery persists the checkpoint periodically until stop is closed.
+func (cp *checkpoint) saveEvery(interval time.Duration, stop chan struct{}, onErr func(error)) {
+	if cp == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := cp.save(); err != nil {
+				onErr(err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// robotsExpander adds checks for parameterized paths listed in each
+// origin's robots.txt. Every origin is fetched once, and the URLs found
+// go through admit, like the input, unless they were already scanned.
+type robotsExpander struct {
+	admit func(paramCheck) bool
+
+	mu      sync.Mutex
+	seen    map[string]bool
+	scanned map[string]bool
+}
+
+func newRobotsExpander(admit func(paramCheck) bool) *robotsExpander {
+	return &robotsExpander{
+		admit:   admit,
+		seen:    make(map[string]bool),
+		scanned: make(map[string]bool),
+	}
+}
+
+// expand is a workerFunc passing c on, followed by checks for the
+// robots.txt entries of its origin the first time that origin is seen.
+func (r *robotsExpander) expand(c paramCheck, output chan paramCheck) {
+	output <- c
+
+	u, err := url.Parse(c.url)
+	if err != nil || u.Host == "" {
+		return
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	r.mu.Lock()
+	r.scanned[c.url] = true
+	if r.seen[origin] {
+		r.mu.Unlock()
+		return
+	}
+	r.seen[origin] = true
+	r.mu.Unlock()
+
+	for _, target := range fetchRobotsURLs(origin) {
+		tc := paramCheck{url: target}
+		r.mu.Lock()
+		scanned := r.scanned[target]
+		r.scanned[target] = true
+		r.mu.Unlock()
+		if !scanned && r.admit(tc) {
+			output <- tc
+		}
+	}
+}
+
+// fetchRobotsURLs returns absolute URLs for the Allow and Disallow rules
+// of origin's robots.txt that carry a query string. Wildcards are dropped
+// and empty values filled with placeholderValue.
+func fetchRobotsURLs(origin string) []string {
+	resp, err := doRequestWithRetries("GET", origin+"/robots.txt", nil, "", 1)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil
+	}
+
+	out := make([]string, 0)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 1024*1024))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field != "allow" && field != "disallow" {
+			continue
+		}
+		value = strings.NewReplacer("*", "", "$", "").Replace(strings.TrimSpace(value))
+		path, query, ok := strings.Cut(value, "?")
+		if !ok || !strings.HasPrefix(path, "/") {
+			continue
+		}
+
+		qs, err := url.ParseQuery(query)
+		if err != nil || len(qs) == 0 {
+			continue
+		}
+		for k, vv := range qs {
+			if len(vv) == 0 || vv[0] == "" {
+				qs.Set(k, placeholderValue)
+			}
+		}
+		target := origin + path + "?" + qs.Encode()
+		if !seen[target] {
+			seen[target] = true
+			out = append(out, target)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// rotatingFile is the -o writer. When -rotate-size or -rotate-every is
+// set, the file is renamed with a timestamp suffix and a fresh one started
+// once either limit is reached. Rotation only happens after a write that
+// ends a line, so line-based formats never split a finding across files.
+type rotatingFile struct {
+	path    string
+	flags   int
+	maxSize int64
+	every   time.Duration
+	file    *os.File
+	size    int64
+	opened  time.Time
+}
+
+func openRotatingFile(path string, appendMode bool, maxSize int64, every time.Duration) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, every: every}
+	f.flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendMode {
+		f.flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, f.flags, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil || n == 0 || p[n-1] != '\n' {
+		return n, err
+	}
+	if (f.maxSize > 0 && f.size >= f.maxSize) || (f.every > 0 && time.Since(f.opened) >= f.every) {
+		if err := f.rotate(); err != nil {
+			return n, fmt.Errorf("rotating %s: %w", f.path, err)
+		}
+	}
+	return n, nil
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	stamp := time.Now().Format("20060102-150405")
+	rotated := f.path + "." + stamp
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+		rotated = fmt.Sprintf("%s.%s.%d", f.path, stamp, i)
+	}
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	// The new file starts empty even in append mode
+	f.flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	return f.open()
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SARIF rule IDs for the kinds of finding kxss reports.
+const (
+	ruleReflectedChars = "reflected-chars"
+	ruleSQLError       = "sql-error"
+	ruleCRLF           = "crlf-injection"
+	ruleSSTI           = "template-injection"
+	ruleCmdInjection   = "command-injection"
+	ruleBlindSQLi      = "blind-sql-injection"
+	ruleNoSQL          = "nosql-injection"
+	rulePathTraversal  = "path-traversal"
+	ruleSSRF           = "ssrf"
+	ruleXXE            = "xxe"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// sarifSink collects findings and writes them as a single SARIF 2.1.0
+// log on close. A finding with both unfiltered characters and a database
+// error becomes one SARIF result per rule.
+type sarifSink struct {
+	w       io.Writer
+	results []sarifResult
+}
+
+func (s *sarifSink) write(r Result) error {
+	if len(r.Unfiltered) > 0 {
+		msg := fmt.Sprintf("Parameter %s (%s) reflects unfiltered characters: %s", r.Param, r.Location, strings.Join(r.Unfiltered, " "))
+		s.results = append(s.results, newSarifResult(r, ruleReflectedChars, "warning", msg))
+	}
+	if r.SQLInjection {
+		msg := fmt.Sprintf("Parameter %s (%s) triggers a database error message", r.Param, r.Location)
+		s.results = append(s.results, newSarifResult(r, ruleSQLError, "error", msg))
+	}
+	if r.CRLFInjection {
+		msg := fmt.Sprintf("Parameter %s (%s) adds a response header when given line breaks", r.Param, r.Location)
+		s.results = append(s.results, newSarifResult(r, ruleCRLF, "error", msg))
+	}
+	if r.BlindSQLInjection != "" {
+		msg := fmt.Sprintf("Parameter %s (%s) changes the database query (%s)", r.Param, r.Location, blindSQLiDetail(r))
+		s.results = append(s.results, newSarifResult(r, ruleBlindSQLi, "error", msg))
+	}
+	if r.NoSQLInjection != "" {
+		msg := fmt.Sprintf("Parameter %s (%s) changes a MongoDB-style query (%s)", r.Param, r.Location, r.NoSQLInjection)
+		s.results = append(s.results, newSarifResult(r, ruleNoSQL, "error", msg))
+	}
+	if r.CommandInjection {
+		msg := fmt.Sprintf("Parameter %s (%s) delays the response when given a sleep command", r.Param, r.Location)
+		s.results = append(s.results, newSarifResult(r, ruleCmdInjection, "error", msg))
+	}
+	if r.PathTraversal {
+		msg := fmt.Sprintf("Parameter %s (%s) reads files outside the intended directory", r.Param, r.Location)
+		s.results = append(s.results, newSarifResult(r, rulePathTraversal, "error", msg))
+	}
+	if len(r.SSRF) > 0 {
+		msg := fmt.Sprintf("Parameter %s (%s) makes the server contact a callback URL (%s)", r.Param, r.Location, strings.Join(r.SSRF, ", "))
+		s.results = append(s.results, newSarifResult(r, ruleSSRF, "error", msg))
+	}
+	if r.XXE != "" {
+		msg := fmt.Sprintf("Parameter %s (%s) is in an XML body whose parser resolves external entities (%s)", r.Param, r.Location, xxeDetail(r))
+		s.results = append(s.results, newSarifResult(r, ruleXXE, "error", msg))
+	}
+	if r.SSTI {
+		msg := fmt.Sprintf("Parameter %s (%s) is evaluated as a template expression (%s)", r.Param, r.Location, r.TemplateEngine)
+		s.results = append(s.results, newSarifResult(r, ruleSSTI, "error", msg))
+	}
+	return nil
+}
+
+func newSarifResult(r Result, rule, level, msg string) sarifResult {
+	var loc sarifLocation
+	loc.PhysicalLocation.ArtifactLocation.URI = r.URL
+	loc.LogicalLocations = []sarifLogicalLocation{{Name: r.Param, Kind: "parameter"}}
+	return sarifResult{
+		RuleID:    rule,
+		Level:     level,
+		Message:   sarifMessage{msg},
+		Locations: []sarifLocation{loc},
+		Properties: map[string]any{
+			"param":      r.Param,
+			"location":   r.Location,
+			"unfiltered": r.Unfiltered,
+			"severity":   r.Severity,
+		},
+	}
+}
+
+func (s *sarifSink) close() error {
+	run := sarifRun{Results: s.results}
+	if run.Results == nil {
+		run.Results = []sarifResult{}
+	}
+	run.Tool.Driver.Name = "kxss"
+	run.Tool.Driver.InformationURI = "https://github.com/secfb/kxss"
+	run.Tool.Driver.Rules = []sarifRule{
+		newSarifRule(ruleReflectedChars, "UnfilteredReflection", "Reflected parameter lets special characters through unfiltered", "warning"),
+		newSarifRule(ruleSQLError, "DatabaseErrorMessage", "Special characters in a parameter trigger a database error message", "error"),
+		newSarifRule(ruleCRLF, "CRLFInjection", "Line breaks in a parameter end a response header and start a new one", "error"),
+		newSarifRule(ruleBlindSQLi, "BlindSQLInjection", "A parameter changes the database query without an error message", "error"),
+		newSarifRule(ruleNoSQL, "NoSQLInjection", "Query operators or conditions in a parameter change a NoSQL database query", "error"),
+		newSarifRule(ruleCmdInjection, "CommandInjection", "Shell commands in a parameter are run on the server", "error"),
+		newSarifRule(rulePathTraversal, "PathTraversal", "Traversal sequences in a parameter read arbitrary files", "error"),
+		newSarifRule(ruleSSRF, "ServerSideRequestForgery", "A URL in a parameter is fetched by the server", "error"),
+		newSarifRule(ruleXXE, "XMLExternalEntity", "The XML parser resolves external entities declared in the request", "error"),
+		newSarifRule(ruleSSTI, "TemplateInjection", "Template expressions in a parameter are evaluated on the server", "error"),
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	jsonData, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(s.w, string(jsonData))
+	return err
+}
+
+func newSarifRule(id, name, desc, level string) sarifRule {
+	r := sarifRule{ID: id, Name: name, ShortDescription: sarifMessage{desc}}
+	r.DefaultConfiguration.Level = level
+	return r
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scopeFile is the on-disk layout of a --scope file:
+//
+//	include:
+//	  domains: ["*.example.com", "example.com"]
+//	  cidrs: ["203.0.113.0/24"]
+//	  paths: ["^/app/"]
+//	exclude:
+//	  domains: ["admin.example.com"]
+//	  paths: ["logout"]
+type scopeFile struct {
+	Include scopeRuleFile `yaml:"include"`
+	Exclude scopeRuleFile `yaml:"exclude"`
+}
+
+type scopeRuleFile struct {
+	Domains []string `yaml:"domains"`
+	CIDRs   []string `yaml:"cidrs"`
+	Paths   []string `yaml:"paths"`
+}
+
+type scopeRules struct {
+	domains []string
+	cidrs   []*net.IPNet
+	paths   []*regexp.Regexp
+}
+
+// scopeSet decides which URLs may be requested. A URL is in scope when it
+// matches the include rules (or there are none) and matches no exclude
+// rule.
+type scopeSet struct {
+	include scopeRules
+	exclude scopeRules
+}
+
+// scope is consulted before every request; nil allows everything.
+var scope *scopeSet
+
+func loadScope(path string) (*scopeSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f scopeFile
+	if err := yaml.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+
+	include, err := compileScopeRules(f.Include)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	exclude, err := compileScopeRules(f.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	return &scopeSet{include: include, exclude: exclude}, nil
+}
+
+func compileScopeRules(f scopeRuleFile) (scopeRules, error) {
+	var r scopeRules
+	for _, d := range f.Domains {
+		r.domains = append(r.domains, strings.ToLower(d))
+	}
+	for _, c := range f.CIDRs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return r, err
+		}
+		r.cidrs = append(r.cidrs, n)
+	}
+	for _, p := range f.Paths {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return r, err
+		}
+		r.paths = append(r.paths, re)
+	}
+	return r, nil
+}
+
+// allows reports whether rawURL may be requested.
+func (s *scopeSet) allows(rawURL string) bool {
+	if s == nil {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if s.exclude.matchesHost(host) || s.exclude.matchesPath(u.Path) {
+		return false
+	}
+
+	// Include rules must match on every dimension that has rules
+	if (len(s.include.domains) > 0 || len(s.include.cidrs) > 0) && !s.include.matchesHost(host) {
+		return false
+	}
+	if len(s.include.paths) > 0 && !s.include.matchesPath(u.Path) {
+		return false
+	}
+	return true
+}
+
+// matchesHost checks host against the domain patterns and, for literal IP
+// hosts, the CIDR ranges. Hostnames are never resolved.
+func (r scopeRules) matchesHost(host string) bool {
+	for _, d := range r.domains {
+		if matchDomain(d, host) {
+			return true
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range r.cidrs {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r scopeRules) matchesPath(path string) bool {
+	for _, re := range r.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain matches host against pattern, where a leading "*." matches
+// any number of subdomains but not the bare domain itself.
+func matchDomain(pattern, host string) bool {
+	if rest, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+rest)
+	}
+	return host == pattern
+}
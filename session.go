@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogoutPattern matches the Location of redirects that mean the
+// session has expired.
+const defaultLogoutPattern = `(?i)/(log-?in|sign-?in|log-?on)\b`
+
+// sessionRefreshInterval is the least time between two logins, however
+// many workers see an expired session.
+const sessionRefreshInterval = 30 * time.Second
+
+// sessionRefresher logs in again whenever a response shows the session
+// has expired: a 401, or a redirect to a URL matching logoutPattern, from
+// the origin of the login request. It either replays a raw login request,
+// whose Set-Cookie headers land in the cookie jar, or runs a script
+// printing "Name: value" headers to send from then on.
+type sessionRefresher struct {
+	loginURL      string
+	loginOrigin   string // empty for a login script, which may serve any origin
+	login         *requestTemplate
+	script        string
+	logoutPattern *regexp.Regexp
+
+	mu   sync.Mutex // held while logging in
+	gen  int        // bumped after every login
+	last time.Time  // of the last login
+
+	steadyMu sync.Mutex
+	steady   map[string]bool // endpoints answering the same after a login, by URL without query
+
+	headerMu sync.RWMutex
+	header   http.Header // from the login script
+}
+
+// session is set by -login or -login-script; nil never refreshes.
+var session *sessionRefresher
+
+func newSessionRefresher(loginFile, script, scheme, logoutPattern string) (*sessionRefresher, error) {
+	re, err := regexp.Compile(logoutPattern)
+	if err != nil {
+		return nil, err
+	}
+	s := &sessionRefresher{script: script, logoutPattern: re, header: make(http.Header), steady: make(map[string]bool)}
+	if loginFile != "" {
+		s.loginURL, s.login, err = readRawRequest(loginFile, scheme)
+		if err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(s.loginURL)
+		if err != nil {
+			return nil, err
+		}
+		s.loginOrigin = u.Scheme + "://" + u.Host
+		if httpClient.Jar == nil {
+			jar, err := cookiejar.New(nil)
+			if err != nil {
+				return nil, err
+			}
+			httpClient.Jar = jar
+		}
+	}
+	return s, nil
+}
+
+// generation identifies the current session, to be passed to refresh.
+func (s *sessionRefresher) generation() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen
+}
+
+// expired reports whether resp, the answer to a request for u, shows that
+// the session has run out. Other origins than the login request's, and
+// endpoints marked steady, never do.
+func (s *sessionRefresher) expired(u *url.URL, resp *http.Response) bool {
+	if s == nil {
+		return false
+	}
+	if s.loginOrigin != "" && u.Scheme+"://"+u.Host != s.loginOrigin {
+		return false
+	}
+	s.steadyMu.Lock()
+	steady := s.steady[steadyKey(u)]
+	s.steadyMu.Unlock()
+	if steady {
+		return false
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return s.logoutPattern.MatchString(resp.Header.Get("Location"))
+	}
+	return false
+}
+
+// markSteady records that u answered the same after a login as before,
+// so that its answers no longer count as an expired session.
+func (s *sessionRefresher) markSteady(u *url.URL) {
+	s.steadyMu.Lock()
+	s.steady[steadyKey(u)] = true
+	s.steadyMu.Unlock()
+}
+
+func steadyKey(u *url.URL) string {
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// refresh logs in again unless another worker already did so since gen
+// was read, so an expiry seen by many workers at once costs one login.
+// It reports whether the request is worth resending: false when the last
+// login is less than sessionRefreshInterval old and none happened since
+// gen.
+func (s *sessionRefresher) refresh(gen int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gen != gen {
+		return true, nil
+	}
+	if s.gen > 0 && time.Since(s.last) < sessionRefreshInterval {
+		return false, nil
+	}
+	var err error
+	if s.login != nil {
+		err = s.replayLogin()
+	} else {
+		err = s.runScript()
+	}
+	if err != nil {
+		return false, err
+	}
+	if s.gen > 0 {
+		fmt.Fprintf(os.Stderr, "session expired, logged in again\n")
+	}
+	s.gen++
+	s.last = time.Now()
+	return true, nil
+}
+
+func (s *sessionRefresher) replayLogin() error {
+	req, err := http.NewRequest(s.login.Method, s.loginURL, strings.NewReader(s.login.Body))
+	if err != nil {
+		return err
+	}
+	req.Header = requestHeader(req.URL, s.login.Header)
+	addCorrelationHeaders(req.Header)
+	resp, err := sendRequest(req)
+	if err != nil {
+		return fmt.Errorf("login request: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login request: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *sessionRefresher) runScript() error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", s.script)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("login script: %v: %s", err, msg)
+		}
+		return fmt.Errorf("login script: %v", err)
+	}
+	header := make(http.Header)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		name, value, err := parseHeaderLine(line)
+		if err != nil {
+			return fmt.Errorf("login script: %v", err)
+		}
+		header.Add(name, value)
+	}
+	if len(header) == 0 {
+		return fmt.Errorf("login script printed no headers")
+	}
+	s.headerMu.Lock()
+	s.header = header
+	s.headerMu.Unlock()
+	return nil
+}
+
+// apply sets the headers printed by the login script on header.
+func (s *sessionRefresher) apply(header http.Header) {
+	if s == nil {
+		return
+	}
+	s.headerMu.RLock()
+	defer s.headerMu.RUnlock()
+	for k, vv := range s.header {
+		header[k] = vv
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+// Severity levels reported in Result.Severity.
+const (
+	severityHigh   = "high"
+	severityMedium = "medium"
+	severityLow    = "low"
+)
+
+// classifySeverity ranks a finding by what its surviving characters allow:
+// a payload verified by -verify, a database error, a server-side injection
+// check or both angle brackets (new tags) is high, a quote or backtick
+// (breaking out of an attribute or script string) is medium, and anything
+// else is low. It returns "" when nothing was found.
+func classifySeverity(r Result) string {
+	survived := make(map[string]bool, len(r.Unfiltered))
+	for _, c := range r.Unfiltered {
+		survived[c] = true
+	}
+	switch {
+	case r.Verified, r.SQLInjection, len(foundChecks(r)) > 0, survived["<"] && survived[">"]:
+		return severityHigh
+	case survived[`"`], survived["'"], survived["`"]:
+		return severityMedium
+	case len(r.Unfiltered) > 0:
+		return severityLow
+	}
+	return ""
+}
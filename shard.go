@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// shardSpec selects a deterministic subset of the input so the same list
+// can be split across machines. Shards are numbered from 1, so "3/10" is
+// the third of ten.
+type shardSpec struct {
+	index int
+	total int
+}
+
+func parseShard(s string) (shardSpec, error) {
+	i, n, ok := strings.Cut(s, "/")
+	if !ok {
+		return shardSpec{}, fmt.Errorf("shard must look like K/N")
+	}
+	index, err := strconv.Atoi(i)
+	if err != nil {
+		return shardSpec{}, fmt.Errorf("bad shard index %q", i)
+	}
+	total, err := strconv.Atoi(n)
+	if err != nil {
+		return shardSpec{}, fmt.Errorf("bad shard count %q", n)
+	}
+	if total < 1 || index < 1 || index > total {
+		return shardSpec{}, fmt.Errorf("shard %d/%d is out of range", index, total)
+	}
+	return shardSpec{index: index, total: total}, nil
+}
+
+// includes reports whether line belongs to this shard. The zero value
+// includes everything.
+func (s shardSpec) includes(line string) bool {
+	if s.total <= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(line))
+	return int(h.Sum64()%uint64(s.total)) == s.index-1
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// maxSitemapDepth bounds how far nested sitemap indexes are followed.
+const maxSitemapDepth = 3
+
+// sitemapDoc covers both <urlset> and <sitemapindex> documents; only one
+// of the two slices is populated for any given file.
+type sitemapDoc struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapURL returns the /sitemap.xml location for a host or base URL.
+func sitemapURL(target string) (string, error) {
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("no host in %q", target)
+	}
+	if strings.HasSuffix(u.Path, ".xml") || strings.HasSuffix(u.Path, ".xml.gz") {
+		return u.String(), nil
+	}
+	u.Path = "/sitemap.xml"
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// readSitemapURLs downloads the sitemap for target, follows nested
+// sitemap indexes and returns every unique URL that carries query
+// parameters.
+func readSitemapURLs(target string) ([]string, error) {
+	start, err := sitemapURL(target)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+	err = walkSitemap(start, 0, visited, func(loc string) {
+		u, err := url.Parse(loc)
+		if err != nil || u.RawQuery == "" || seen[loc] {
+			return
+		}
+		seen[loc] = true
+		out = append(out, loc)
+	})
+	return out, err
+}
+
+func walkSitemap(loc string, depth int, visited map[string]bool, fn func(string)) error {
+	if depth > maxSitemapDepth || visited[loc] {
+		return nil
+	}
+	visited[loc] = true
+
+	doc, err := fetchSitemap(loc)
+	if err != nil {
+		return err
+	}
+	for _, u := range doc.URLs {
+		fn(strings.TrimSpace(u.Loc))
+	}
+	for _, s := range doc.Sitemaps {
+		nested := strings.TrimSpace(s.Loc)
+		if err := walkSitemap(nested, depth+1, visited, fn); err != nil {
+			return fmt.Errorf("nested sitemap %s: %w", nested, err)
+		}
+	}
+	return nil
+}
+
+func fetchSitemap(loc string) (*sitemapDoc, error) {
+	resp, err := doRequestWithRetries("GET", loc, nil, "", 3)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var r io.Reader = io.LimitReader(resp.Body, 50*1024*1024) // Limit to 50MB, the sitemap protocol maximum
+	if strings.HasSuffix(loc, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
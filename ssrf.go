@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// urlValue matches values that look like a URL or a host and path: the
+// kind of value a server might fetch. A bare host needs a letter-only
+// top-level label and a bare IPv4 address a port or path, so that numbers
+// like 3.14 or 1.2.3.4 do not count.
+var urlValue = regexp.MustCompile(`(?i)^([a-z][a-z0-9+.-]*:)?//|^([a-z0-9-]+\.)+[a-z]{2,63}\.?(:\d+)?(/|$)|^\d{1,3}(\.\d{1,3}){3}(:\d+|/)`)
+
+// fileName matches bare file names, which urlValue takes for hosts.
+var fileName = regexp.MustCompile(`(?i)^[^/:]+\.(pdf|docx?|xlsx?|pptx?|odt|txt|csv|json|xml|ya?ml|ini|conf|log|html?|php|aspx?|jsp|js|css|png|jpe?g|gif|svg|ico|webp|mp[34]|zip|gz|tgz|tar)$`)
+
+// fetchable reports whether v looks like something a server might fetch,
+// see urlValue.
+func fetchable(v string) bool {
+	return urlValue.MatchString(v) && !fileName.MatchString(v)
+}
+
+// ssrfProbe is a callback URL sent to one parameter by -ssrf.
+type ssrfProbe struct {
+	check   paramCheck
+	kind    string // "ssrf", or "xxe" for an external entity under -xxe
+	payload string
+	curl    string
+}
+
+// ssrfDetector sets every parameter with a URL-shaped value to a unique
+// callback URL. Interactions arrive at the -ssrf-listen and -ssrf-dns
+// listeners, or at "kxss listen" or any other collaborator through the
+// -blind-log file.
+type ssrfDetector struct {
+	inj       *blindInjector
+	wait      time.Duration
+	listening bool
+	xxe       bool // also load the callback URL as an external entity in XML bodies
+
+	mu     sync.Mutex
+	probes map[string]ssrfProbe
+	seen   map[string]map[string]bool // by ID, the protocols it came in over
+}
+
+// ssrfCheck has no test: callbacks arrive out of band, see ssrfDetector.
+var ssrfCheck = injectionCheck{
+	label:  "SSRF",
+	found:  func(r Result) bool { return len(r.SSRF) > 0 },
+	detail: func(r Result) string { return strings.Join(r.SSRF, ", ") },
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, ssrfCheck)
+}
+
+func newSSRFDetector(callback, logPath, scanID string, wait time.Duration) (*ssrfDetector, error) {
+	inj, err := newBlindInjector(callback, logPath, scanID)
+	if err != nil {
+		return nil, err
+	}
+	return &ssrfDetector{
+		inj:    inj,
+		wait:   wait,
+		probes: make(map[string]ssrfProbe),
+		seen:   make(map[string]map[string]bool),
+	}, nil
+}
+
+// listen serves callbacks over HTTP on httpAddr and DNS on dnsAddr, each
+// unless empty. DNS answers point at the host of httpAddr when it has one.
+func (s *ssrfDetector) listen(httpAddr, dnsAddr, logPath string) error {
+	log := &injectionLog{path: logPath}
+	if httpAddr != "" {
+		ln, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return err
+		}
+		go http.Serve(ln, callbackHandler("http", log, s.interaction))
+	}
+	if dnsAddr != "" {
+		conn, err := net.ListenPacket("udp", dnsAddr)
+		if err != nil {
+			return err
+		}
+		host, _, _ := net.SplitHostPort(httpAddr)
+		go serveDNS(conn, net.ParseIP(host), log, s.interaction)
+	}
+	s.listening = httpAddr != "" || dnsAddr != ""
+	return nil
+}
+
+func (s *ssrfDetector) interaction(in interaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[in.ID] == nil {
+		s.seen[in.ID] = make(map[string]bool)
+	}
+	s.seen[in.ID][in.Protocol] = true
+}
+
+// inject sends a fresh callback URL to each parameter of c whose value
+// looks like a URL, then passes c on unchanged.
+func (s *ssrfDetector) inject(c paramCheck, output chan paramCheck) {
+	defer func() { output <- c }()
+	for _, pc := range testedParams(c) {
+		if s.xxe {
+			id := newBlindID()
+			if doc, ok := xxeCallback(pc, s.inj.payloadURL(id)); ok {
+				resp, err := sendTest(pc, pc.url, doc)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error sending XXE callback to %s: %s\n", c.url, err)
+				} else {
+					s.add(id, ssrfProbe{check: pc, kind: "xxe", payload: doc, curl: resp.curl})
+				}
+			}
+		}
+		if pc.loc == locHeader || !fetchable(pc.value()) {
+			continue
+		}
+		id := newBlindID()
+		payload := s.inj.payloadURL(id)
+		resp, err := sendReplaced(pc, payload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error sending SSRF callback to %s param %s: %s\n", c.url, pc.param, err)
+			continue
+		}
+		s.add(id, ssrfProbe{check: pc, kind: "ssrf", payload: payload, curl: resp.curl})
+	}
+}
+
+// add logs p under id and remembers it for check.
+func (s *ssrfDetector) add(id string, p ssrfProbe) {
+	s.inj.record(p.check, id, p.kind, p.payload)
+	s.mu.Lock()
+	s.probes[id] = p
+	s.mu.Unlock()
+}
+
+// check waits for late callbacks, then hands a Result to report for each
+// parameter whose callback URL was looked up or fetched. Without listeners
+// of its own there is nothing to wait for.
+func (s *ssrfDetector) check(report func(Result)) {
+	if !s.listening {
+		return
+	}
+	time.Sleep(s.wait)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, protocols := range s.seen {
+		p, ok := s.probes[id]
+		if !ok {
+			continue
+		}
+		r := newResult(p.check)
+		var seen []string
+		for proto := range protocols {
+			seen = append(seen, proto)
+		}
+		sort.Strings(seen)
+		if p.kind == "xxe" {
+			r.XXE = xxeOOB
+			r.XXEProtocols = seen
+		} else {
+			r.SSRF = seen
+		}
+		r.Payload = p.payload
+		r.Curl = p.curl
+		r.Severity = classifySeverity(r)
+		r.Fingerprint = fingerprint(r)
+		report(r)
+	}
+}
+
+func (s *ssrfDetector) close() error {
+	return s.inj.close()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+)
+
+// sstiProduct is what each of sstiProbes evaluates to; unlike 49, or a
+// short number that nonces and IDs turn up by chance, 1337*7331 is
+// unlikely to be on the page already.
+const sstiProduct = "9801547"
+
+// sstiProbe is one template syntax: an arithmetic expression, the
+// engines it narrows things down to when it evaluates, and follow-up
+// expressions telling those engines apart.
+type sstiProbe struct {
+	payload   string
+	engines   string
+	followUps []sstiFollowUp
+}
+
+// sstiFollowUp names engine when payload renders as want.
+type sstiFollowUp struct {
+	payload string
+	want    string
+	engine  string
+}
+
+var sstiProbes = []sstiProbe{
+	{
+		payload: "{{1337*7331}}",
+		engines: "Jinja2, Twig or Nunjucks",
+		followUps: []sstiFollowUp{
+			{"{{7*'7'}}", "7777777", "Jinja2"},
+			{"{{7*'7'}}", "49", "Twig or Nunjucks"},
+		},
+	},
+	{
+		payload: "${1337*7331}",
+		engines: "FreeMarker, Mako or Java EL",
+		followUps: []sstiFollowUp{
+			{`${"kxssti"?upper_case}`, "KXSSTI", "FreeMarker"},
+			{`${"kxssti".upper()}`, "KXSSTI", "Mako"},
+			{`${"kxssti".toUpperCase()}`, "KXSSTI", "Java EL"},
+		},
+	},
+	{
+		payload: "#{1337*7331}",
+		engines: "Ruby, Pug or Java EL",
+		followUps: []sstiFollowUp{
+			{`#{"kxssti".upcase}`, "KXSSTI", "Ruby"},
+			{`#{"kxssti".toUpperCase()}`, "KXSSTI", "Pug or Java EL"},
+		},
+	},
+}
+
+var sstiCheck = injectionCheck{
+	label:  "Template Injection",
+	found:  func(r Result) bool { return r.SSTI },
+	detail: func(r Result) string { return r.TemplateEngine },
+	test:   testSSTI,
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, sstiCheck)
+}
+
+// testSSTI appends each of sstiProbes to c's parameter and reports the
+// first whose product appears in the response but not in the unmodified
+// one, requested twice, naming the engine from the follow-ups that
+// evaluate too.
+func testSSTI(c paramCheck, r *Result) error {
+	base, err := sendInjected(c, "")
+	if err != nil {
+		return err
+	}
+	again, err := sendInjected(c, "")
+	if err != nil {
+		return err
+	}
+	if strings.Contains(base.body, sstiProduct) || strings.Contains(again.body, sstiProduct) {
+		return nil
+	}
+	for _, p := range sstiProbes {
+		resp, err := sendInjected(c, p.payload)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(resp.body, sstiProduct) {
+			continue
+		}
+		r.SSTI = true
+		r.Payload = p.payload
+		r.Curl = resp.curl
+		r.Evidence = snippetAround(resp.body, sstiProduct, evidenceContext)
+		r.TemplateEngine = p.engines
+		// Follow-ups may share a payload and differ in what they expect
+		bodies := make(map[string]string)
+		for _, f := range p.followUps {
+			if strings.Contains(base.body, f.want) {
+				continue
+			}
+			body, ok := bodies[f.payload]
+			if !ok {
+				resp, err := sendInjected(c, f.payload)
+				if err != nil {
+					return err
+				}
+				body = resp.body
+				bodies[f.payload] = body
+			}
+			if strings.Contains(body, f.want) {
+				r.TemplateEngine = f.engine
+				break
+			}
+		}
+		return nil
+	}
+	return nil
+}
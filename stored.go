@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// storedProbe is a unique canary sent to one parameter by -stored-views.
+type storedProbe struct {
+	canary string
+	check  paramCheck
+}
+
+// storedXSS finds stored XSS in two phases: during the scan every
+// parameter gets a unique canary, and once the scan is over the view
+// pages are fetched and searched for the canaries.
+type storedXSS struct {
+	views []string
+
+	mu     sync.Mutex
+	probes []storedProbe
+}
+
+func newStoredXSS(viewsPath string) (*storedXSS, error) {
+	views, err := readWordlist(viewsPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(views) == 0 {
+		return nil, fmt.Errorf("no view pages in %s", viewsPath)
+	}
+	return &storedXSS{views: views}, nil
+}
+
+// storedPayload brackets every special character with canary, so that a
+// view page showing it tells which ones were stored and rendered as is.
+func storedPayload(canary string) string {
+	return canary + strings.Join(specialChars, "") + canary
+}
+
+// inject sends a fresh canary to each parameter of c in turn, then passes
+// c on unchanged.
+func (s *storedXSS) inject(c paramCheck, output chan paramCheck) {
+	defer func() { output <- c }()
+	for _, pc := range testedParams(c) {
+		canary := "kxss" + newBlindID()
+		if err := sendAppended(pc, storedPayload(canary)); err != nil {
+			fmt.Fprintf(os.Stderr, "error sending stored canary to %s param %s: %s\n", c.url, pc.param, err)
+			continue
+		}
+		s.mu.Lock()
+		s.probes = append(s.probes, storedProbe{canary: canary, check: pc})
+		s.mu.Unlock()
+	}
+}
+
+// check fetches every view page and hands a Result to report for each
+// canary that shows up on it with special characters left unfiltered.
+func (s *storedXSS) check(report func(Result)) {
+	for _, view := range s.views {
+		resp, err := doRequestWithRetries("GET", view, nil, "", 3)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error fetching view page %s: %s\n", view, err)
+			continue
+		}
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		resp.Body.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading view page %s: %s\n", view, err)
+			continue
+		}
+		page := string(b)
+		for _, p := range s.probes {
+			_, rest, ok := strings.Cut(page, p.canary)
+			if !ok {
+				continue
+			}
+			between, _, _ := strings.Cut(rest, p.canary)
+			if r := storedResult(p, view, page, between); len(r.Unfiltered) > 0 {
+				report(r)
+			}
+		}
+	}
+}
+
+// storedResult describes p's canary found on view with between following
+// it, up to the closing canary.
+func storedResult(p storedProbe, view, page, between string) Result {
+	c := p.check
+	r := Result{
+		URL:        c.url,
+		Param:      c.param,
+		Location:   c.loc,
+		StoredAt:   view,
+		Unfiltered: []string{},
+		Evidence:   snippetAround(page, p.canary, evidenceContext),
+		Context:    reflectionContexts(page, p.canary),
+	}
+	if c.tmpl != nil {
+		r.Method = c.tmpl.Method
+		r.Body = c.tmpl.Body
+	}
+	for _, char := range specialChars {
+		if strings.Contains(between, char) {
+			r.Unfiltered = append(r.Unfiltered, char)
+		}
+	}
+	r.Severity = classifySeverity(r)
+	r.Curl = curlCommand(c)
+	r.Fingerprint = fingerprint(r)
+	return r
+}
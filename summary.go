@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// summaryTopN caps the per-host and per-parameter tables of -summary.
+const summaryTopN = 20
+
+// paramSpread is a parameter name and how many hosts it was vulnerable on.
+type paramSpread struct {
+	Param string
+	Hosts int
+}
+
+// paramsAcrossHosts returns parameter names found vulnerable on more than
+// one host, most widespread first.
+func paramsAcrossHosts(results []Result) []paramSpread {
+	hosts := make(map[string]map[string]bool)
+	for _, r := range results {
+		if hosts[r.Param] == nil {
+			hosts[r.Param] = make(map[string]bool)
+		}
+		hosts[r.Param][resultHost(r)] = true
+	}
+	out := make([]paramSpread, 0)
+	for param, h := range hosts {
+		if len(h) > 1 {
+			out = append(out, paramSpread{param, len(h)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Hosts != out[j].Hosts {
+			return out[i].Hosts > out[j].Hosts
+		}
+		return out[i].Param < out[j].Param
+	})
+	return out
+}
+
+// writeSummary prints the aggregate view of a run: findings per host,
+// the most common unfiltered characters, the kinds of injection found and
+// parameters that were vulnerable on several hosts.
+func writeSummary(w io.Writer, results []Result) error {
+	hosts := summarizeHosts(results)
+	chars := countChars(results)
+	sort.SliceStable(chars, func(i, j int) bool { return chars[i].Count > chars[j].Count })
+	params := paramsAcrossHosts(results)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "\nSummary: %d findings on %d hosts\n", len(results), len(hosts))
+
+	fmt.Fprintf(tw, "\nHOST\tFINDINGS\tPARAMS\tSQL ERRORS\n")
+	for i, h := range hosts {
+		if i == summaryTopN {
+			fmt.Fprintf(tw, "... and %d more hosts\n", len(hosts)-summaryTopN)
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", h.Host, h.Findings, h.Params, h.SQLInjection)
+	}
+
+	fmt.Fprintf(tw, "\nCHAR\tFINDINGS\n")
+	for _, c := range chars {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Char, c.Count)
+	}
+
+	if kinds := countKinds(results); len(kinds) > 0 {
+		fmt.Fprintf(tw, "\nKIND\tFINDINGS\n")
+		for _, k := range kinds {
+			fmt.Fprintf(tw, "%s\t%d\n", k.Kind, k.Count)
+		}
+	}
+
+	if len(params) > 0 {
+		fmt.Fprintf(tw, "\nPARAM\tHOSTS\n")
+		for i, p := range params {
+			if i == summaryTopN {
+				fmt.Fprintf(tw, "... and %d more params\n", len(params)-summaryTopN)
+				break
+			}
+			fmt.Fprintf(tw, "%s\t%d\n", p.Param, p.Hosts)
+		}
+	}
+	return tw.Flush()
+}
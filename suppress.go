@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// fingerprint identifies a finding by host, parameter and the set of
+// unfiltered characters, so it stays stable across paths and scans.
+// Injection checks that hit are included, so that a CRLF and a template
+// injection in one parameter can be suppressed separately.
+func fingerprint(r Result) string {
+	chars := append([]string(nil), r.Unfiltered...)
+	sort.Strings(chars)
+	key := resultHost(r) + "\x00" + r.Param + "\x00" + strings.Join(chars, "")
+	for _, ic := range foundChecks(r) {
+		key += "\x00" + ic.label
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// suppressSet holds fingerprints of accepted findings given with
+// -suppress.
+type suppressSet map[string]bool
+
+// loadSuppress reads a JSON array of fingerprint strings, or earlier
+// results (a JSON array or stream of objects) whose fingerprints are all
+// suppressed.
+func loadSuppress(path string) (suppressSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := make(suppressSet)
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, fp := range list {
+			s[strings.TrimSpace(fp)] = true
+		}
+		return s, nil
+	}
+
+	results, err := readResults(path)
+	if err != nil {
+		return nil, fmt.Errorf("expected a JSON array of fingerprints or kxss results: %w", err)
+	}
+	for _, r := range results {
+		if r.Fingerprint == "" {
+			r.Fingerprint = fingerprint(r)
+		}
+		s[r.Fingerprint] = true
+	}
+	return s, nil
+}
+
+// suppressed reports whether r was accepted earlier. A nil set suppresses
+// nothing.
+func (s suppressSet) suppressed(r Result) bool {
+	return s[r.Fingerprint]
+}
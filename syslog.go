@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// syslogFacility is local0; findings are logged as warnings, or errors
+// for high severity.
+const (
+	syslogFacility = 16
+	syslogError    = 3
+	syslogWarning  = 4
+	// syslogSDID is the structured data ID carrying finding fields.
+	syslogSDID = "kxss@32473"
+	// syslogTime has the at most six fractional digits RFC 5424 allows.
+	syslogTime = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// syslogSink sends each finding as an RFC 5424 message to a collector
+// given as udp://, tcp:// or tls://host:port. Stream transports use
+// octet-counting framing (RFC 6587).
+type syslogSink struct {
+	network  string
+	addr     string
+	conn     net.Conn
+	hostname string
+}
+
+func newSyslogSink(dest string) (*syslogSink, error) {
+	network, addr, ok := strings.Cut(dest, "://")
+	if !ok || (network != "udp" && network != "tcp" && network != "tls") {
+		return nil, fmt.Errorf("syslog destination must be udp://, tcp:// or tls://host:port")
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	s := &syslogSink{network: network, addr: addr, hostname: hostname}
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *syslogSink) dial() error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if s.network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial(s.network, s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *syslogSink) write(r Result) error {
+	msg := s.format(r)
+	if s.network != "udp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		// The collector may have dropped an idle stream; retry once
+		s.conn.Close()
+		if derr := s.dial(); derr != nil {
+			return err
+		}
+		_, err = s.conn.Write([]byte(msg))
+		return err
+	}
+	return nil
+}
+
+// format builds the RFC 5424 message for r.
+func (s *syslogSink) format(r Result) string {
+	severity := syslogWarning
+	if r.Severity == severityHigh {
+		severity = syslogError
+	}
+	labels := findingLabels(r)
+	sd := fmt.Sprintf(`[%s url="%s" param="%s" location="%s" unfiltered="%s" sql_injection="%t" kinds="%s" severity="%s"]`,
+		syslogSDID, sdEscape(r.URL), sdEscape(r.Param), sdEscape(string(r.Location)),
+		sdEscape(strings.Join(r.Unfiltered, " ")), r.SQLInjection, sdEscape(strings.Join(labels, "; ")), sdEscape(r.Severity))
+	text := fmt.Sprintf("param %s (%s) on %s unfiltered: %s", r.Param, r.Location, r.URL, strings.Join(r.Unfiltered, " "))
+	if len(labels) > 0 {
+		text = strings.Join(labels, ", ") + ", " + text
+	}
+	return fmt.Sprintf("<%d>1 %s %s kxss %d finding %s %s",
+		syslogFacility*8+severity, time.Now().UTC().Format(syslogTime), s.hostname, os.Getpid(), sd, text)
+}
+
+// sdEscape escapes a structured data parameter value.
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+func (s *syslogSink) close() error {
+	return s.conn.Close()
+}
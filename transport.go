@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// setProxy routes the shared transport through proxy, an http://,
+// https://, socks5:// or socks5h:// URL with optional user:pass.
+func setProxy(proxy string) error {
+	u, err := parseProxyURL(proxy)
+	if err != nil {
+		return err
+	}
+	transport.Proxy = http.ProxyURL(u)
+	return nil
+}
+
+func parseProxyURL(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL has no host")
+	}
+	return u, nil
+}
+
+// setHTTPVersion picks the protocols the shared transport may use: "1.1"
+// (the default), "2" to offer HTTP/2 over TLS and fall back to HTTP/1.1,
+// or "2-only" to always speak HTTP/2, with prior-knowledge h2c for
+// http:// URLs.
+func setHTTPVersion(version string) error {
+	var p http.Protocols
+	switch version {
+	case "1.1":
+		p.SetHTTP1(true)
+	case "2":
+		p.SetHTTP1(true)
+		p.SetHTTP2(true)
+	case "2-only":
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	default:
+		return fmt.Errorf("HTTP version must be 1.1, 2 or 2-only")
+	}
+	transport.Protocols = &p
+	return nil
+}
+
+// setTLSFingerprint makes TLS handshakes look like the named browser's.
+// It is nil in builds without uTLS.
+var setTLSFingerprint func(name string) error
+
+// setSourceIP sends scan requests from ip, one of this machine's
+// addresses, instead of the one the routing table picks.
+func setSourceIP(ip string) error {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return fmt.Errorf("%q is not an IP address", ip)
+	}
+	dialer.LocalAddr = &net.TCPAddr{IP: addr}
+	return nil
+}
+
+// setInterface sends scan requests from the address of the named network
+// interface, preferring IPv4.
+func setInterface(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return err
+	}
+	var ip net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			ip = ipnet.IP
+			break
+		}
+		if ip == nil {
+			ip = ipnet.IP
+		}
+	}
+	if ip == nil {
+		return fmt.Errorf("interface %s has no usable address", name)
+	}
+	dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	return nil
+}
+
+// curlTransportArgs mirrors the settings of the shared transport as curl
+// options for the reproduction commands.
+var curlTransportArgs []string
+
+// setInsecure turns off certificate verification for scan requests.
+func setInsecure() {
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	curlTransportArgs = append(curlTransportArgs, "-k")
+}
+
+// setCABundle trusts the PEM certificates in path in addition to the
+// system roots.
+func setCABundle(path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found")
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	curlTransportArgs = append(curlTransportArgs, "--cacert", shellQuote(path))
+	return nil
+}
+
+// setClientCert presents the PEM certificate and key to servers that ask
+// for a client certificate. keyFile may be empty when certFile holds both.
+func setClientCert(certFile, keyFile string) error {
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	curlTransportArgs = append(curlTransportArgs, "--cert", shellQuote(certFile), "--key", shellQuote(keyFile))
+	return nil
+}
+
+// setSNI sends name as the TLS server name, and verifies the certificate
+// against it, instead of the URL host.
+func setSNI(name string) {
+	transport.TLSClientConfig.ServerName = name
+}
+
+// setUnixSocket connects every scan request to the Unix domain socket at
+// path, whatever host the URL names. The URL host is still sent as the
+// Host header.
+func setUnixSocket(path string) {
+	d := &net.Dialer{Timeout: dialer.Timeout}
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, "unix", path)
+	}
+	curlTransportArgs = append(curlTransportArgs, "--unix-socket", shellQuote(path))
+}
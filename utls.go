@@ -0,0 +1,101 @@
+//go:build !noutls
+
+package main
+
+// Browser TLS fingerprints for -tls-fingerprint. Build with -tags noutls
+// to leave them out.
+import (
+	"context"
+	"fmt"
+	"net"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+var utlsHellos = map[string]utls.ClientHelloID{
+	"chrome":  utls.HelloChrome_Auto,
+	"firefox": utls.HelloFirefox_Auto,
+	"safari":  utls.HelloSafari_Auto,
+	"edge":    utls.HelloEdge_Auto,
+	"ios":     utls.HelloIOS_Auto,
+}
+
+func init() {
+	setTLSFingerprint = setUTLSFingerprint
+}
+
+// setUTLSFingerprint makes the shared transport open TLS connections with
+// the ClientHello of the named browser.
+func setUTLSFingerprint(name string) error {
+	id, ok := utlsHellos[name]
+	if !ok {
+		return fmt.Errorf("unknown fingerprint %q (chrome, firefox, safari, edge or ios)", name)
+	}
+	if _, err := browserHelloSpec(id); err != nil {
+		return err
+	}
+
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := transport.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		std := transport.TLSClientConfig
+		cfg := &utls.Config{
+			ServerName:         std.ServerName,
+			InsecureSkipVerify: std.InsecureSkipVerify,
+			RootCAs:            std.RootCAs,
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		for _, c := range std.Certificates {
+			cfg.Certificates = append(cfg.Certificates, utls.Certificate{
+				Certificate: c.Certificate,
+				PrivateKey:  c.PrivateKey,
+				Leaf:        c.Leaf,
+			})
+		}
+
+		// Extensions carry per-handshake state, so each connection gets
+		// a fresh spec
+		spec, err := browserHelloSpec(id)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		uconn := utls.UClient(conn, cfg, utls.HelloCustom)
+		if err := uconn.ApplyPreset(&spec); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := uconn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return uconn, nil
+	}
+	return nil
+}
+
+// browserHelloSpec returns the ClientHello of id with ALPN cut down to
+// http/1.1, as the transport cannot speak HTTP/2 over a uTLS connection.
+func browserHelloSpec(id utls.ClientHelloID) (utls.ClientHelloSpec, error) {
+	spec, err := utls.UTLSIdToSpec(id)
+	if err != nil {
+		return spec, err
+	}
+	exts := spec.Extensions[:0]
+	for _, ext := range spec.Extensions {
+		switch e := ext.(type) {
+		case *utls.ALPNExtension:
+			e.AlpnProtocols = []string{"http/1.1"}
+		case *utls.ApplicationSettingsExtension:
+			// ALPS only makes sense alongside h2
+			continue
+		}
+		exts = append(exts, ext)
+	}
+	spec.Extensions = exts
+	return spec, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// verifiedResult is a previously reported finding together with the
+// outcome of testing it again.
+type verifiedResult struct {
+	Result
+	Status string `json:"status"`
+}
+
+const (
+	statusPresent = "present"
+	statusFixed   = "fixed"
+	statusSkipped = "skipped"
+	statusError   = "error"
+)
+
+// readResults loads findings written by -j: either a stream of JSON
+// objects (pretty-printed or one per line) or a single JSON array.
+func readResults(path string) ([]Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return []Result{}, nil
+			}
+			return nil, err
+		}
+		if b[0] != ' ' && b[0] != '\n' && b[0] != '\r' && b[0] != '\t' {
+			break
+		}
+		br.ReadByte()
+	}
+
+	dec := json.NewDecoder(br)
+	if b, _ := br.Peek(1); b[0] == '[' {
+		var results []Result
+		if err := dec.Decode(&results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	results := make([]Result, 0)
+	for {
+		var r Result
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+}
+
+// runVerify implements "kxss verify": every finding in the input is tested
+// again and reported as still present or fixed. It takes the scan's
+// request flags, so that findings behind a login or a proxy are sent the
+// same way.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var inputFile string
+	var outputFile string
+	var numWorkers int
+	var jsonOutput bool
+	var req requestFlags
+	fs.StringVar(&inputFile, "i", "", "JSON results from a previous scan to re-test")
+	fs.StringVar(&outputFile, "o", "", "file to write output to")
+	fs.IntVar(&numWorkers, "w", 40, "number of worker goroutines")
+	fs.BoolVar(&jsonOutput, "j", false, "output results in JSON format")
+	req.register(fs)
+	fs.Parse(args)
+
+	if inputFile == "" {
+		fmt.Fprintf(os.Stderr, "verify needs a results file given with -i\n")
+		os.Exit(1)
+	}
+	if numWorkers < 1 {
+		fmt.Fprintf(os.Stderr, "number of workers must be at least 1\n")
+		os.Exit(1)
+	}
+
+	req.apply()
+
+	results, err := readResults(inputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading results %s: %s\n", inputFile, err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating output file %s: %s\n", outputFile, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan Result)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				v := verifyResult(r)
+				mu.Lock()
+				writeVerified(out, v, jsonOutput)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range results {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// verifyResult re-runs the character checks for r. Body parameters are
+// sent with the method and body stored in r, and skipped for results
+// written before those were recorded; header and path findings reuse
+// the method and body when there are any. Findings from injection checks
+// alone, such as CRLF injection, re-run just those checks; out-of-band
+// ones like SSRF are skipped.
+func verifyResult(r Result) verifiedResult {
+	c := paramCheck{url: r.URL, param: r.Param, loc: locQuery}
+	if r.Location == locHeader || r.Location == locPath {
+		c.loc = r.Location
+		if r.Method != "" {
+			c.tmpl = &requestTemplate{Method: r.Method, Header: make(http.Header), Body: r.Body}
+		}
+	} else if r.Location != "" && r.Location != locQuery {
+		ct := bodyContentType(r.Location, r.Body)
+		if ct == "" || r.Body == "" {
+			return verifiedResult{Result: r, Status: statusSkipped}
+		}
+		header := make(http.Header)
+		header.Set("Content-Type", ct)
+		c.loc = r.Location
+		c.tmpl = &requestTemplate{Method: r.Method, Header: header, Body: r.Body}
+	}
+	if checks := foundChecks(r); len(checks) > 0 && len(r.Unfiltered) == 0 && !r.SQLInjection {
+		for _, ic := range checks {
+			if ic.test == nil {
+				return verifiedResult{Result: r, Status: statusSkipped}
+			}
+		}
+		if now := runInjectionChecks(c, checks); len(now) > 0 {
+			return verifiedResult{Result: now[0], Status: statusPresent}
+		}
+		return verifiedResult{Result: newResult(c), Status: statusFixed}
+	}
+	// Same gate as a scan: without the canary coming back the parameter
+	// is no longer reflected and single characters would only match noise
+	wasReflected, isError, err := checkAppend(c, reflectionCanary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error re-testing url %s with param %s: %s\n", r.URL, r.Param, err)
+		return verifiedResult{Result: r, Status: statusError}
+	}
+	if !wasReflected && !isError {
+		return verifiedResult{Result: Result{URL: r.URL, Param: r.Param, Location: c.loc, Method: r.Method, Body: r.Body, Unfiltered: []string{}}, Status: statusFixed}
+	}
+	now := checkChars(c)
+	if now.isFinding() {
+		return verifiedResult{Result: now, Status: statusPresent}
+	}
+	return verifiedResult{Result: now, Status: statusFixed}
+}
+
+func writeVerified(out io.Writer, v verifiedResult, jsonOutput bool) {
+	if jsonOutput {
+		jsonData, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error marshaling JSON for %s: %s\n", v.URL, err)
+			return
+		}
+		fmt.Fprintln(out, string(jsonData))
+		return
+	}
+	param := v.Param
+	for _, label := range findingLabels(v.Result) {
+		param += " [" + label + "]"
+	}
+	fmt.Fprintf(out, "URL: %s Param: %s [%s] Unfiltered: %v\n", v.URL, param, v.Status, v.Unfiltered)
+}
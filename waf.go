@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// blockStatuses are the status codes WAFs answer blocked requests with.
+var blockStatuses = map[int]bool{
+	http.StatusForbidden:      true,
+	http.StatusNotAcceptable:  true,
+	http.StatusNotImplemented: true,
+	999:                       true,
+}
+
+// blockSignatures are phrases of common WAF block pages, lower-cased.
+var blockSignatures = []string{
+	"access denied", "request rejected", "attention required", "mod_security",
+	"web application firewall", "the requested url was rejected", "has been blocked",
+	"incapsula incident", "sucuri website firewall", "akamai reference",
+}
+
+// isBlockPage reports whether a probe response with the given status and
+// body looks like a WAF block page rather than the page the base request
+// (with baseStatus) got.
+func isBlockPage(status, baseStatus int, body string) bool {
+	if status == baseStatus || status < 400 {
+		return false
+	}
+	if blockStatuses[status] {
+		return true
+	}
+	lower := strings.ToLower(body)
+	for _, sig := range blockSignatures {
+		if strings.Contains(lower, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// evasionHit records that a character was only seen unfiltered once the
+// request was mutated with an evasion technique or, in Result.Decoded,
+// sent in an encoding the application decodes.
+type evasionHit struct {
+	Char      string `json:"char" xml:"char"`
+	Technique string `json:"technique" xml:"technique"`
+}
+
+// evasion mutates the probe for a blocked character. It returns the check
+// to send, the URL and body to send it with, and the marker to look for,
+// or ok false when the technique does not apply to c.
+type evasion struct {
+	name  string
+	apply func(c paramCheck, char string) (pc paramCheck, urlStr, body, marker string, ok bool)
+}
+
+// evasions are tried in order on characters whose probe was blocked. The
+// probe carries the canary in front of the character so that only the
+// mutated value, not the page's own markup, can count as a reflection.
+var evasions = []evasion{
+	{"case", evadeCase},
+	{"comment", evadeComment},
+	{"chunked", evadeChunked},
+	{"split", evadeSplit},
+}
+
+// caseTag follows the character in the "case" evasion.
+const caseTag = "ScRiPt"
+
+// evadeCase sends the character followed by a tag name in mixed case, as
+// in <ScRiPt, percent-encoded with lower-case hex digits. Rule sets that
+// match keywords or the canonical %3C form case-sensitively miss both.
+func evadeCase(c paramCheck, char string) (paramCheck, string, string, string, bool) {
+	marker := reflectionCanary + char
+	escaped := strings.ToLower(url.QueryEscape(marker)) + caseTag
+	body := templateBody(c)
+	switch c.loc {
+	case locQuery:
+		u, err := url.Parse(c.url)
+		if err != nil {
+			return c, "", "", "", false
+		}
+		q, ok := editRawQuery(u.RawQuery, c.param, func(v string) string { return v + escaped })
+		return c, replaceRawQuery(c.url, q), body, marker, ok
+	case locBody:
+		b, ok := editRawQuery(body, c.param, func(v string) string { return v + escaped })
+		return c, c.url, b, marker, ok
+	case locPath:
+		u, err := editPathSegment(c.url, c.param, func(s string) string { return s + escaped })
+		return c, u, body, marker, err == nil
+	}
+	return c, "", "", "", false
+}
+
+// evadeComment puts an empty comment between the canary and the
+// character, breaking up the sequences signatures look for.
+func evadeComment(c paramCheck, char string) (paramCheck, string, string, string, bool) {
+	marker := reflectionCanary + "/**/" + char
+	urlStr, body, err := c.withSuffix(marker)
+	return c.withHeaderSuffix("/**/" + char), urlStr, body, marker, err == nil
+}
+
+// evadeChunked sends a body probe with chunked transfer encoding, which
+// some WAFs do not reassemble before inspecting.
+func evadeChunked(c paramCheck, char string) (paramCheck, string, string, string, bool) {
+	if rawSocket || templateBody(c) == "" {
+		return c, "", "", "", false
+	}
+	marker := reflectionCanary + char
+	urlStr, body, err := c.withSuffix(marker)
+	c = c.withHeaderSuffix(char)
+	c.chunked = true
+	return c, urlStr, body, marker, err == nil
+}
+
+// evadeSplit leaves the parameter alone and sends the probe in a second
+// parameter of the same name, for servers that join or prefer the last
+// of repeated parameters while the WAF checks the first.
+func evadeSplit(c paramCheck, char string) (paramCheck, string, string, string, bool) {
+	marker := reflectionCanary + char
+	extra := url.QueryEscape(c.param) + "=" + url.QueryEscape(marker)
+	body := templateBody(c)
+	switch c.loc {
+	case locQuery:
+		u, err := url.Parse(c.url)
+		if err != nil {
+			return c, "", "", "", false
+		}
+		return c, replaceRawQuery(c.url, u.RawQuery+"&"+extra), body, marker, true
+	case locBody:
+		return c, c.url, body + "&" + extra, marker, true
+	}
+	return c, "", "", "", false
+}
+
+func templateBody(c paramCheck) string {
+	if c.tmpl == nil {
+		return ""
+	}
+	return c.tmpl.Body
+}
+
+// evadeBlock retries char on c with each evasion in turn and returns the
+// name of the first one under which the character comes back, or "".
+func evadeBlock(c paramCheck, char string) (string, error) {
+	for _, ev := range evasions {
+		pc, urlStr, body, marker, ok := ev.apply(c, char)
+		if !ok {
+			continue
+		}
+		p, err := probeAppend(pc, urlStr, body, marker)
+		if err != nil {
+			return "", err
+		}
+		if p.reflected {
+			return ev.name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// webhookQueueSize bounds how many findings may wait for delivery before
+// the scan is slowed down by a slow collector.
+const webhookQueueSize = 100
+
+// webhookSink POSTs every finding to a URL as soon as it is found.
+// Delivery happens on its own goroutine so a slow endpoint does not hold
+// up result writing; close waits for the queue to drain.
+type webhookSink struct {
+	url     string
+	header  http.Header
+	payload func(Result) ([]byte, error)
+	client  *http.Client
+	queue   chan Result
+	done    chan struct{}
+}
+
+// newWebhookSink sends each Result as a JSON object with the given extra
+// headers.
+func newWebhookSink(url string, headers []string) (*webhookSink, error) {
+	header := make(http.Header)
+	for _, h := range headers {
+		name, value, err := parseHeaderLine(h)
+		if err != nil {
+			return nil, err
+		}
+		header.Add(name, value)
+	}
+	return startWebhookSink(url, header, func(r Result) ([]byte, error) {
+		return json.Marshal(r)
+	}), nil
+}
+
+// startWebhookSink starts delivering JSON documents built by payload to
+// url.
+func startWebhookSink(url string, header http.Header, payload func(Result) ([]byte, error)) *webhookSink {
+	header.Set("Content-Type", "application/json")
+	s := &webhookSink{
+		url:     url,
+		header:  header,
+		payload: payload,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		queue:   make(chan Result, webhookQueueSize),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *webhookSink) run() {
+	defer close(s.done)
+	for r := range s.queue {
+		if err := s.post(r); err != nil {
+			fmt.Fprintf(os.Stderr, "error sending webhook for %s: %s\n", r.URL, err)
+		}
+	}
+}
+
+func (s *webhookSink) post(r Result) error {
+	jsonData, err := s.payload(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header = s.header.Clone()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) write(r Result) error {
+	s.queue <- r
+	return nil
+}
+
+func (s *webhookSink) close() error {
+	close(s.queue)
+	<-s.done
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// xmlSink streams findings as <result> elements inside a single <results>
+// document. The root element is opened with the first finding so that an
+// empty scan still produces a well-formed document on close.
+type xmlSink struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+var xmlResultsStart = xml.StartElement{Name: xml.Name{Local: "results"}}
+
+func (s *xmlSink) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	if _, err := io.WriteString(s.w, xml.Header); err != nil {
+		return err
+	}
+	s.enc = xml.NewEncoder(s.w)
+	s.enc.Indent("", "  ")
+	return s.enc.EncodeToken(xmlResultsStart)
+}
+
+func (s *xmlSink) write(r Result) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.enc.EncodeElement(r, xml.StartElement{Name: xml.Name{Local: "result"}}); err != nil {
+		return err
+	}
+	return s.enc.Flush()
+}
+
+func (s *xmlSink) close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.enc.EncodeToken(xmlResultsStart.End()); err != nil {
+		return err
+	}
+	if err := s.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.w, "\n")
+	return err
+}
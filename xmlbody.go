@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// xmlValue is the text of a leaf element or an attribute value in an XML
+// body, named by an XPath such as /soap:Envelope/soap:Body/login/user or
+// /order/item[2]/@id.
+type xmlValue struct {
+	name       string
+	value      string // decoded
+	start, end int    // raw bytes of the value in the body
+	cdata      bool
+}
+
+// xmlValues lists the injectable values of an XML body in document order.
+// Namespace declarations and elements with mixed content are left out.
+func xmlValues(body string) ([]xmlValue, error) {
+	type frame struct {
+		name     string
+		path     string
+		counts   map[string]int
+		hasChild bool
+		texts    []xmlValue
+	}
+	stack := []*frame{{counts: make(map[string]int)}}
+	var out []xmlValue
+
+	dec := xml.NewDecoder(strings.NewReader(body))
+	for {
+		before := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		after := int(dec.InputOffset())
+
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			top.hasChild = true
+			name := xmlQName(t.Name)
+			top.counts[name]++
+			path := top.path + "/" + name
+			if n := top.counts[name]; n > 1 {
+				path += fmt.Sprintf("[%d]", n)
+			}
+			raw := body[before:after]
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				if s, e, ok := xmlAttrValueRange(raw, xmlQName(a.Name)); ok {
+					out = append(out, xmlValue{name: path + "/@" + xmlQName(a.Name), value: a.Value, start: before + s, end: before + e})
+				}
+			}
+			stack = append(stack, &frame{name: name, path: path, counts: make(map[string]int)})
+		case xml.CharData:
+			if len(stack) > 1 && strings.TrimSpace(string(t)) != "" {
+				top.texts = append(top.texts, xmlValue{
+					name:  top.path,
+					value: string(t),
+					start: before,
+					end:   after,
+					cdata: strings.HasPrefix(body[before:], "<![CDATA["),
+				})
+			}
+		case xml.EndElement:
+			if len(stack) == 1 || xmlQName(t.Name) != top.name {
+				return nil, fmt.Errorf("unexpected </%s>", xmlQName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+			if !top.hasChild && len(top.texts) == 1 {
+				out = append(out, top.texts[0])
+			}
+		}
+	}
+}
+
+// xmlAttrValueRange finds the value of attribute name, inside its quotes,
+// in the raw start tag.
+func xmlAttrValueRange(tag, name string) (int, int, bool) {
+	re := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+	m := re.FindStringSubmatchIndex(tag)
+	if m == nil {
+		return 0, 0, false
+	}
+	return m[2] + 1, m[3] - 1, true
+}
+
+func xmlQName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// xmlParams lists the injectable values of an XML body.
+func xmlParams(body string) []bodyParam {
+	values, err := xmlValues(body)
+	if err != nil {
+		return nil
+	}
+	out := make([]bodyParam, len(values))
+	for i, v := range values {
+		out[i] = bodyParam{name: v.name, loc: locXML, value: v.value}
+	}
+	return out
+}
+
+// editXML rewrites the value named by path in body with edit, escaping
+// the result so the server's parser sees exactly what edit returned. Only
+// the bytes of that value change.
+func editXML(body, path string, edit func(string) string) (string, error) {
+	values, err := xmlValues(body)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range values {
+		if v.name != path {
+			continue
+		}
+		s := edit(v.value)
+		var repl string
+		if v.cdata && !strings.Contains(s, "]]>") {
+			repl = "<![CDATA[" + s + "]]>"
+		} else {
+			var b strings.Builder
+			xml.EscapeText(&b, []byte(s))
+			repl = b.String()
+		}
+		return body[:v.start] + repl + body[v.end:], nil
+	}
+	return "", fmt.Errorf("%s not found in XML body", path)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXMLValues(t *testing.T) {
+	tests := []struct {
+		body string
+		want map[string]string
+	}{
+		{
+			`<login><user>bob</user><pass>x</pass></login>`,
+			map[string]string{"/login/user": "bob", "/login/pass": "x"},
+		},
+		{
+			`<order id="7"><item sku='a1'>one</item><item>two</item></order>`,
+			map[string]string{"/order/@id": "7", "/order/item/@sku": "a1", "/order/item": "one", "/order/item[2]": "two"},
+		},
+		{
+			`<s:Envelope xmlns:s="urn:s"><s:Body><q>a &amp; b</q></s:Body></s:Envelope>`,
+			map[string]string{"/s:Envelope/s:Body/q": "a & b"},
+		},
+		{
+			`<a><b><![CDATA[<raw>]]></b></a>`,
+			map[string]string{"/a/b": "<raw>"},
+		},
+		{
+			// mixed content is left out
+			`<p>text <b>bold</b> more</p>`,
+			map[string]string{"/p/b": "bold"},
+		},
+	}
+	for _, tt := range tests {
+		values, err := xmlValues(tt.body)
+		if err != nil {
+			t.Errorf("xmlValues(%q): %v", tt.body, err)
+			continue
+		}
+		got := make(map[string]string)
+		for _, v := range values {
+			got[v.name] = v.value
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("xmlValues(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestXMLValuesMalformed(t *testing.T) {
+	for _, body := range []string{`<a><b>x</a>`, `</a>`, `<a x="1></a>`} {
+		if _, err := xmlValues(body); err == nil {
+			t.Errorf("xmlValues(%q) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestEditXML(t *testing.T) {
+	suffix := func(s string) string { return s + `"<x>&` }
+	tests := []struct {
+		body, path, want string
+	}{
+		{
+			`<a><b>v</b><c>w</c></a>`, "/a/b",
+			`<a><b>v&#34;&lt;x&gt;&amp;</b><c>w</c></a>`,
+		},
+		{
+			`<a id="1" n='2'><b>v</b></a>`, "/a/@id",
+			`<a id="1&#34;&lt;x&gt;&amp;" n='2'><b>v</b></a>`,
+		},
+		{
+			`<a id="1" n='2'><b>v</b></a>`, "/a/@n",
+			`<a id="1" n='2&#34;&lt;x&gt;&amp;'><b>v</b></a>`,
+		},
+		{
+			`<a><b><![CDATA[v]]></b></a>`, "/a/b",
+			`<a><b><![CDATA[v"<x>&]]></b></a>`,
+		},
+		{
+			`<a><b>1</b><b>2</b></a>`, "/a/b[2]",
+			`<a><b>1</b><b>2&#34;&lt;x&gt;&amp;</b></a>`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := editXML(tt.body, tt.path, suffix)
+		if err != nil {
+			t.Errorf("editXML(%q, %q): %v", tt.body, tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("editXML(%q, %q) = %q, want %q", tt.body, tt.path, got, tt.want)
+		}
+		// The server has to read back exactly what edit returned
+		values, err := xmlValues(got)
+		if err != nil {
+			t.Errorf("edited body %q does not parse: %v", got, err)
+			continue
+		}
+		for _, v := range values {
+			if v.name == tt.path && v.value[len(v.value)-len(`"<x>&`):] != `"<x>&` {
+				t.Errorf("edited %s reads back as %q", tt.path, v.value)
+			}
+		}
+	}
+}
+
+func TestEditXMLCDATAEnd(t *testing.T) {
+	// ]]> cannot go in a CDATA section, so the value is escaped instead
+	got, err := editXML(`<a><![CDATA[v]]></a>`, "/a", func(s string) string { return s + "]]>" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<a>v]]&gt;</a>`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEditXMLMissing(t *testing.T) {
+	if _, err := editXML(`<a><b>v</b></a>`, "/a/c", func(s string) string { return s }); err == nil {
+		t.Error("editing a missing path succeeded")
+	}
+}
